@@ -16,7 +16,11 @@ func main() {
 
 	logger := models.MakeLogger()
 
-	err = a.RunServer(cfg, logger) // запускаем gRPC сервер (агент)
+	if cfg.AgentMode == models.AgentPull {
+		err = a.RunPuller(cfg, logger) // забираем задачи из очереди оркестратора
+	} else {
+		err = a.RunServer(cfg, logger) // запускаем gRPC сервер (агент)
+	}
 	if err != nil {
 		log.Fatal(err.Error())
 	}
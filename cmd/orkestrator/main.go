@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
 	h "github.com/ArtemiySps/calc_go_final/internal/orkestrator/http"
@@ -20,7 +22,24 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
-	api.ConnectToServer() // коннектимся к gRPC серверу (агенту)
+	if cfg.DispatchMode == models.DispatchPush {
+		api.ConnectToServer() // коннектимся к gRPC серверу (агенту)
+	}
+
+	// продолжаем вычисление выражений, прерванных остановкой оркестратора. до запуска серверов,
+	// чтобы новые выражения не приняли за прерванные; вычисления продолжатся,
+	// когда агенты успеют заново зарегистрироваться
+	if _, err := api.Recover(time.Duration(cfg.HeartbeatInterval) * time.Millisecond); err != nil {
+		logger.Error("recovery failed: " + err.Error())
+	}
+
+	go api.WatchAgents(context.Background()) // отслеживаем потерянных агентов
+
+	go func() {
+		if err := api.RunGRPCServer(); err != nil { // запускаем gRPC сервер для агентов, забирающих задачи
+			log.Fatal(err.Error())
+		}
+	}()
 
 	logger = models.MakeLogger()
 	//serverErr := make(chan error, 1)
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 
@@ -11,10 +12,19 @@ import (
 type Config struct {
 	ComputingPower int
 	OperationTimes map[string]int
+	// число операций, которые могут ждать свободного воркера
+	QueueSize int
 
 	OrkestratorPort string
 	AgentPort       string
 	AgentHost       string
+
+	// server - агент принимает операции от оркестратора (Calculation),
+	// pull - агент сам забирает задачи из очереди оркестратора
+	AgentMode           string
+	OrkestratorHost     string
+	OrkestratorGRPCPort string
+	PollInterval        int
 }
 
 func NewConfig() (*Config, error) {
@@ -24,6 +34,10 @@ func NewConfig() (*Config, error) {
 	}
 
 	computingPower, _ := strconv.Atoi(os.Getenv("COMPUTING_POWER"))
+	queueSize, err := strconv.Atoi(os.Getenv("QUEUE_SIZE"))
+	if err != nil || queueSize <= 0 {
+		queueSize = 100
+	}
 
 	orkestratorPort := os.Getenv("PORT_ORKESTRATOR")
 	agentPort := os.Getenv("PORT_AGENT")
@@ -46,13 +60,45 @@ func NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, models.ErrDivisionTime
 	}
+	operationTimes["^"], err = strconv.Atoi(os.Getenv("TIME_POWER_MS"))
+	if err != nil {
+		return nil, models.ErrPowerTime
+	}
+	operationTimes["%"], err = strconv.Atoi(os.Getenv("TIME_MODULO_MS"))
+	if err != nil {
+		return nil, models.ErrModuloTime
+	}
+	operationTimes["//"], err = strconv.Atoi(os.Getenv("TIME_INT_DIVISION_MS"))
+	if err != nil {
+		return nil, models.ErrIntDivisionTime
+	}
+	for fn, env := range models.FunctionTimeEnvs {
+		operationTimes[fn], err = strconv.Atoi(os.Getenv(env))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", models.ErrFunctionTime, env)
+		}
+	}
+
+	agentMode := os.Getenv("AGENT_MODE")
+	if agentMode == "" {
+		agentMode = models.AgentServer
+	}
+	if agentMode != models.AgentServer && agentMode != models.AgentPull {
+		return nil, models.ErrAgentMode
+	}
+	pollInterval, _ := strconv.Atoi(os.Getenv("POLL_INTERVAL_MS"))
 
 	cfg := &Config{
-		ComputingPower:  computingPower,
-		OperationTimes:  operationTimes,
-		OrkestratorPort: orkestratorPort,
-		AgentPort:       agentPort,
-		AgentHost:       agentHost,
+		ComputingPower:      computingPower,
+		OperationTimes:      operationTimes,
+		QueueSize:           queueSize,
+		OrkestratorPort:     orkestratorPort,
+		AgentPort:           agentPort,
+		AgentHost:           agentHost,
+		AgentMode:           agentMode,
+		OrkestratorHost:     os.Getenv("HOST_ORKESTRATOR"),
+		OrkestratorGRPCPort: os.Getenv("PORT_ORKESTRATOR_GRPC"),
+		PollInterval:        pollInterval,
 	}
 
 	return cfg, nil
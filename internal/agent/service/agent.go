@@ -9,91 +9,132 @@ import (
 
 	"github.com/ArtemiySps/calc_go_final/internal/agent/config"
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
 	"go.uber.org/zap"
 
 	pb "github.com/ArtemiySps/calc_go_final/proto"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type Agent struct {
 	Config *config.Config
 	log    *zap.Logger
 
-	resp AgResponse
+	// воркеры и очередь операций (см. pool.go)
+	pool *workerPool
 
-	pb.CalcServiceServer
-}
+	// ID агента у оркестратора (см. Announce)
+	mu sync.Mutex
+	id string
 
-type AgResponse struct {
-	result float32
-	err    error
+	pb.UnimplementedCalcServiceServer
 }
 
 func NewAgent(cfg *config.Config, logger *zap.Logger) *Agent {
-	return &Agent{
+	a := &Agent{
 		Config: cfg,
 		log:    logger,
 	}
+	a.startPool()
+	return a
 }
 
+// вычисление одной операции. операция ставится в очередь и вычисляется первым
+// свободным воркером; если очередь заполнена, возвращается код RESOURCE_EXHAUSTED.
+// результат и ошибка хранятся только в этом вызове, поэтому одновременные запросы
+// не влияют друг на друга. при отмене ctx (оркестратор отменил вызов) операция
+// не вычисляется или прерывается, и возвращается код CANCELLED
 func (a *Agent) Calculation(ctx context.Context, in *pb.TaskRequest) (*pb.ResResponse, error) {
-	ctx, cancel := context.WithCancel(context.Background())
-	wg := &sync.WaitGroup{}
-	resultChan := make(chan float32, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resultChan := make(chan numeric.Value, 1)
 	errorChan := make(chan error, 1)
 
+	err := a.pool.submit(&task{ctx: ctx, in: in, resultChan: resultChan, errorChan: errorChan})
+	if err != nil {
+		a.log.Warn("task rejected: " + err.Error())
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
 	a.log.Info("got task. calculating...")
 
-	wg.Add(a.Config.ComputingPower)
-	go func() {
-		defer wg.Done()
-
-		for range a.Config.ComputingPower {
-			go a.Worker(ctx, in, resultChan, errorChan)
-		}
-	}()
-
+	var res numeric.Value
 	select {
-	case res := <-resultChan:
-		cancel()
-		a.resp.result = res
+	case res = <-resultChan:
 	case err := <-errorChan:
-		cancel()
-		a.resp.err = err
+		a.log.Info("calculating failed: " + err.Error())
+		return nil, err
+	case <-ctx.Done():
+		a.log.Info("calculating cancelled")
+		return nil, status.FromContextError(ctx.Err()).Err()
 	}
-	wg.Wait()
 
 	a.log.Info("calculating completed! waiting for the next task...")
 
-	return &pb.ResResponse{Res: float32(a.resp.result)}, a.resp.err
+	resp := pb.NewResResponse(res.Float)
+	resp.ResExact = res.Exact
+	if in.Mode == models.ModeComplex {
+		resp.ResImag = &res.Imag
+	}
+	return resp, nil
 }
 
-// воркер
-func (a *Agent) Worker(ctx context.Context, in *pb.TaskRequest, resultChan chan<- float32, errorChan chan<- error) {
-	time.Sleep(time.Duration(a.Config.OperationTimes[in.Opr]) * time.Millisecond)
+// воркер: вычисляет операцию и отправляет ровно одно сообщение - результат или ошибку.
+// если ctx отменен, операция не вычисляется и ничего не отправляется. срок операции
+// (in.TimeoutMs) отсчитывается с начала вычисления; не уложившаяся в него операция
+// прерывается с ошибкой ErrOperationTimeout
+func (a *Agent) Worker(ctx context.Context, in *pb.TaskRequest, resultChan chan<- numeric.Value, errorChan chan<- error) {
+	opCtx := ctx
+	if in.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		opCtx, cancel = context.WithTimeout(ctx, time.Duration(in.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
 
 	select {
-	case <-ctx.Done():
+	case <-opCtx.Done():
+		if ctx.Err() == nil {
+			errorChan <- models.ErrOperationTimeout
+		}
 		return
+	case <-time.After(time.Duration(a.Config.OperationTimes[in.Opr]) * time.Millisecond):
+	}
+
+	res, err := compute(in)
+
+	select {
+	case <-ctx.Done():
 	default:
-		switch in.Opr {
-		case "+":
-			resultChan <- in.Arg1 + in.Arg2
-		case "-":
-			resultChan <- in.Arg1 - in.Arg2
-		case "*":
-			resultChan <- in.Arg1 * in.Arg2
-		case "/":
-			if in.Arg2 == 0 {
-				errorChan <- models.ErrDivisionByZero
-			}
-			resultChan <- in.Arg1 / in.Arg2
-		default:
-			errorChan <- models.ErrUnexpectedSymbol
+		if err != nil {
+			errorChan <- err
+			return
 		}
+		resultChan <- res
 	}
 }
 
+// функция для вычисления операции (см. numeric.Calculate). в точных режимах
+// операция вычисляется по точной записи аргументов
+func compute(in *pb.TaskRequest) (numeric.Value, error) {
+	x, y := in.Args()
+
+	mode := in.Mode
+	if mode == "" {
+		mode = models.ModeFloat
+	}
+	rounding := in.Rounding
+	if rounding == "" {
+		rounding = models.RoundHalfEven
+	}
+
+	return numeric.Calculate(mode, in.Opr,
+		numeric.Value{Float: x, Imag: in.GetArg1Imag(), Exact: in.Arg1Exact},
+		numeric.Value{Float: y, Imag: in.GetArg2Imag(), Exact: in.Arg2Exact},
+		numeric.Options{Scale: int(in.Scale), Rounding: rounding})
+}
+
 func RunServer(cfg *config.Config, logger *zap.Logger) error {
 	logger.Info("Server (agent) is starting on address: " + cfg.AgentHost + ":" + cfg.AgentPort)
 
@@ -111,6 +152,18 @@ func RunServer(cfg *config.Config, logger *zap.Logger) error {
 
 	pb.RegisterCalcServiceServer(grpcServer, calcServiceAgent)
 
+	// агент сообщает оркестратору свой адрес, чтобы тот начал отправлять ему операции
+	if cfg.OrkestratorHost != "" {
+		orkAddr := fmt.Sprintf("%s:%s", cfg.OrkestratorHost, cfg.OrkestratorGRPCPort)
+		conn, err := grpc.NewClient(orkAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return models.ErrConnectingGRPC
+		}
+		defer conn.Close()
+
+		go calcServiceAgent.keepRegistered(context.Background(), pb.NewCalcServiceClient(conn), addr)
+	}
+
 	if err := grpcServer.Serve(lis); err != nil {
 		return models.ErrServingGRPC
 	}
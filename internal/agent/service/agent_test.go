@@ -2,14 +2,18 @@ package agent
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ArtemiySps/calc_go_final/internal/agent/config"
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
 	pb "github.com/ArtemiySps/calc_go_final/proto"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // тест для Calculation
@@ -17,7 +21,7 @@ func TestAgent_Calculation(t *testing.T) {
 	tests := []struct {
 		name        string
 		req         *pb.TaskRequest
-		expectedRes float32
+		expectedRes float64
 		expectedErr error
 	}{
 		{
@@ -38,6 +42,72 @@ func TestAgent_Calculation(t *testing.T) {
 			expectedRes: 0,
 			expectedErr: models.ErrDivisionByZero,
 		},
+		{
+			name:        "Power",
+			req:         &pb.TaskRequest{Opr: "^", Arg1: 2, Arg2: 10},
+			expectedRes: 1024,
+			expectedErr: nil,
+		},
+		{
+			name:        "Power of negative base to fractional exponent",
+			req:         &pb.TaskRequest{Opr: "^", Arg1: -8, Arg2: 0.5},
+			expectedRes: 0,
+			expectedErr: models.ErrBadPower,
+		},
+		{
+			name:        "Modulo",
+			req:         &pb.TaskRequest{Opr: "%", Arg1: -7, Arg2: 3},
+			expectedRes: 2,
+			expectedErr: nil,
+		},
+		{
+			name:        "Modulo by zero",
+			req:         &pb.TaskRequest{Opr: "%", Arg1: 7, Arg2: 0},
+			expectedRes: 0,
+			expectedErr: models.ErrModuloByZero,
+		},
+		{
+			name:        "Integer division",
+			req:         &pb.TaskRequest{Opr: "//", Arg1: -7, Arg2: 2},
+			expectedRes: -4,
+			expectedErr: nil,
+		},
+		{
+			name:        "Integer division by zero",
+			req:         &pb.TaskRequest{Opr: "//", Arg1: 7, Arg2: 0},
+			expectedRes: 0,
+			expectedErr: models.ErrDivisionByZero,
+		},
+		{
+			name:        "Square root",
+			req:         &pb.TaskRequest{Opr: "sqrt", Arg1: 16},
+			expectedRes: 4,
+			expectedErr: nil,
+		},
+		{
+			name:        "Square root of negative number",
+			req:         &pb.TaskRequest{Opr: "sqrt", Arg1: -1},
+			expectedRes: 0,
+			expectedErr: models.ErrSqrtOfNegative,
+		},
+		{
+			name:        "Logarithm of zero",
+			req:         &pb.TaskRequest{Opr: "log", Arg1: 0},
+			expectedRes: 0,
+			expectedErr: models.ErrLogOfNonPositive,
+		},
+		{
+			name:        "Round",
+			req:         &pb.TaskRequest{Opr: "round", Arg1: -2.5},
+			expectedRes: -3,
+			expectedErr: nil,
+		},
+		{
+			name:        "Max",
+			req:         &pb.TaskRequest{Opr: "max", Arg1: 3, Arg2: 4},
+			expectedRes: 4,
+			expectedErr: nil,
+		},
 		{
 			name:        "Unexpected symbol",
 			req:         &pb.TaskRequest{Opr: "?", Arg1: 1, Arg2: 2},
@@ -53,11 +123,18 @@ func TestAgent_Calculation(t *testing.T) {
 			cfg := &config.Config{
 				ComputingPower: 1,
 				OperationTimes: map[string]int{
-					"+": 0,
-					"-": 0,
-					"*": 0,
-					"/": 0,
-					"?": 0,
+					"+":     0,
+					"-":     0,
+					"*":     0,
+					"/":     0,
+					"^":     0,
+					"%":     0,
+					"//":    0,
+					"sqrt":  0,
+					"log":   0,
+					"round": 0,
+					"max":   0,
+					"?":     0,
 				},
 			}
 
@@ -67,19 +144,269 @@ func TestAgent_Calculation(t *testing.T) {
 			if tt.expectedErr != nil {
 				assert.ErrorIs(t, err, tt.expectedErr)
 			} else {
-				assert.Equal(t, tt.expectedRes, res.Res)
+				assert.Equal(t, tt.expectedRes, res.Result())
 				assert.NoError(t, err)
 			}
 		})
 	}
 }
 
+// числа передаются в double-полях без потери точности, а запросы старых
+// оркестраторов (только float-поля) по-прежнему обрабатываются
+func TestAgent_Calculation_Precision(t *testing.T) {
+	cfg := &config.Config{
+		ComputingPower: 1,
+		OperationTimes: map[string]int{"+": 0, "*": 0},
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	cents, count := 19.99, 3.0
+	tests := []struct {
+		name        string
+		req         *pb.TaskRequest
+		expectedRes float64
+	}{
+		{
+			name:        "Integer above float32 precision",
+			req:         pb.NewTaskRequest("+", 16777217, 1),
+			expectedRes: 16777218,
+		},
+		{
+			name:        "Cents",
+			req:         pb.NewTaskRequest("*", cents, count),
+			expectedRes: cents * count,
+		},
+		{
+			name:        "Legacy request",
+			req:         &pb.TaskRequest{Opr: "+", Arg1: 0.5, Arg2: 0.25},
+			expectedRes: 0.75,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := agent.Calculation(context.Background(), tt.req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRes, res.Result())
+			// float-поле заполняется для старых оркестраторов
+			assert.Equal(t, float32(tt.expectedRes), res.Res)
+		})
+	}
+}
+
+// в режиме decimal операция вычисляется по точной записи аргументов
+func TestAgent_Calculation_Decimal(t *testing.T) {
+	decimal := func(op, x, y string, scale int32, rounding string) *pb.TaskRequest {
+		req := pb.NewTaskRequest(op, 0, 0)
+		req.Mode = models.ModeDecimal
+		req.Arg1Exact, req.Arg2Exact = x, y
+		req.Scale, req.Rounding = scale, rounding
+		return req
+	}
+
+	tests := []struct {
+		name        string
+		req         *pb.TaskRequest
+		expectedRes string
+		expectedErr error
+	}{
+		{
+			name:        "Addition",
+			req:         decimal("+", "0.1", "0.2", 20, ""),
+			expectedRes: "0.3",
+		},
+		{
+			name:        "Division with scale",
+			req:         decimal("/", "2", "3", 5, models.RoundHalfUp),
+			expectedRes: "0.66667",
+		},
+		{
+			name:        "Division by zero",
+			req:         decimal("/", "1", "0", 5, ""),
+			expectedErr: models.ErrDivisionByZero,
+		},
+		{
+			name:        "Unsupported operation",
+			req:         decimal("sin", "1", "0", 5, ""),
+			expectedErr: models.ErrUnsupportedOp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ComputingPower: 1,
+				OperationTimes: map[string]int{},
+			}
+			agent := NewAgent(cfg, zap.NewNop())
+
+			res, err := agent.Calculation(context.Background(), tt.req)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRes, res.ResExact)
+		})
+	}
+}
+
+// в режиме complex мнимые части аргументов и результата передаются в *_imag-полях
+func TestAgent_Calculation_Complex(t *testing.T) {
+	cfg := &config.Config{
+		ComputingPower: 1,
+		OperationTimes: map[string]int{},
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	req := pb.NewTaskRequest("*", 2, 1)
+	req.Mode = models.ModeComplex
+	im1, im2 := 3.0, -1.0
+	req.Arg1Imag, req.Arg2Imag = &im1, &im2
+
+	res, err := agent.Calculation(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, 5.0, res.Result())
+	if assert.NotNil(t, res.ResImag) {
+		assert.Equal(t, 1.0, *res.ResImag)
+	}
+	assert.Equal(t, "5+1i", res.ResExact)
+}
+
+// одновременные запросы получают свои результаты, а ошибка одного запроса
+// не попадает в ответы на другие (go test -race)
+func TestAgent_Calculation_Concurrent(t *testing.T) {
+	const requests = 500
+	cfg := &config.Config{
+		ComputingPower: 4,
+		OperationTimes: map[string]int{},
+		QueueSize:      requests,
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			x := float64(i)
+			if i%2 == 0 {
+				_, err := agent.Calculation(context.Background(), pb.NewTaskRequest("/", x, 0))
+				assert.ErrorIs(t, err, models.ErrDivisionByZero)
+				return
+			}
+			res, err := agent.Calculation(context.Background(), pb.NewTaskRequest("/", x, 2))
+			if assert.NoError(t, err) {
+				assert.Equal(t, x/2, res.Result())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ошибка предыдущего запроса не возвращается в следующем
+func TestAgent_Calculation_ErrorDoesNotLeak(t *testing.T) {
+	cfg := &config.Config{
+		ComputingPower: 1,
+		OperationTimes: map[string]int{},
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	_, err := agent.Calculation(context.Background(), pb.NewTaskRequest("/", 1, 0))
+	assert.ErrorIs(t, err, models.ErrDivisionByZero)
+
+	res, err := agent.Calculation(context.Background(), pb.NewTaskRequest("+", 1, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, res.Result())
+}
+
+// одновременно вычисляется не больше ComputingPower операций
+func TestAgent_Calculation_ComputingPower(t *testing.T) {
+	cfg := &config.Config{
+		ComputingPower: 2,
+		OperationTimes: map[string]int{"+": 50},
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := agent.Calculation(context.Background(), pb.NewTaskRequest("+", 1, 2))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// 6 операций по 50 мс на 2 воркерах - три операции подряд: не меньше 150 мс,
+	// но заметно быстрее, чем 300 мс при вычислении по одной
+	elapsed := time.Since(start)
+	assert.GreaterOrEqual(t, elapsed, 150*time.Millisecond)
+	assert.Less(t, elapsed, 275*time.Millisecond)
+}
+
+// срок операции отсчитывается с начала вычисления: операции, ждавшие в очереди
+// дольше своего срока, вычисляются, а не уложившаяся в срок операция прерывается
+func TestAgent_Calculation_OperationTimeout(t *testing.T) {
+	cfg := &config.Config{
+		ComputingPower: 1,
+		OperationTimes: map[string]int{"+": 50, "*": 10000},
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := pb.NewTaskRequest("+", 1, 2)
+			req.TimeoutMs = 80
+			res, err := agent.Calculation(context.Background(), req)
+			assert.NoError(t, err)
+			assert.Equal(t, 3.0, res.Result())
+		}()
+	}
+	wg.Wait()
+
+	req := pb.NewTaskRequest("*", 1, 2)
+	req.TimeoutMs = 20
+	start := time.Now()
+	_, err := agent.Calculation(context.Background(), req)
+	assert.ErrorIs(t, err, models.ErrOperationTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// при отмене ctx операция прерывается, возвращается код CANCELLED,
+// а воркер освобождается для следующих операций
+func TestAgent_Calculation_Cancel(t *testing.T) {
+	cfg := &config.Config{
+		ComputingPower: 1,
+		OperationTimes: map[string]int{"+": 10000, "-": 0},
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := agent.Calculation(ctx, pb.NewTaskRequest("+", 1, 2))
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.Less(t, time.Since(start), time.Second)
+
+	res, err := agent.Calculation(context.Background(), pb.NewTaskRequest("-", 5, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, res.Result())
+}
+
 // тесты для Worker
 func TestAgent_Worker(t *testing.T) {
 	tests := []struct {
 		name        string
 		req         *pb.TaskRequest
-		expectedRes float32
+		expectedRes float64
 		expectedErr error
 	}{
 		{
@@ -94,6 +421,12 @@ func TestAgent_Worker(t *testing.T) {
 			expectedRes: 5,
 			expectedErr: nil,
 		},
+		{
+			name:        "Division by zero",
+			req:         &pb.TaskRequest{Opr: "/", Arg1: 10, Arg2: 0},
+			expectedRes: 0,
+			expectedErr: models.ErrDivisionByZero,
+		},
 	}
 
 	for _, tt := range tests {
@@ -109,19 +442,23 @@ func TestAgent_Worker(t *testing.T) {
 
 			agent := NewAgent(cfg, logger)
 
-			resultChan := make(chan float32, 1)
+			resultChan := make(chan numeric.Value, 1)
 			errorChan := make(chan error, 1)
 
 			agent.Worker(context.Background(), tt.req, resultChan, errorChan)
 
 			select {
 			case res := <-resultChan:
-				assert.Equal(t, tt.expectedRes, res)
+				assert.Equal(t, tt.expectedRes, res.Float)
 			case err := <-errorChan:
 				assert.ErrorIs(t, err, tt.expectedErr)
 			case <-time.After(100 * time.Millisecond):
 				t.Fatal("Worker timed out")
 			}
+
+			// воркер отправляет ровно одно сообщение
+			assert.Empty(t, resultChan)
+			assert.Empty(t, errorChan)
 		})
 	}
 }
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+)
+
+// размер очереди, если QueueSize не задан
+const defaultQueueSize = 100
+
+// операция в очереди агента. результат или ошибка отправляются в каналы запроса
+type task struct {
+	ctx        context.Context
+	in         *pb.TaskRequest
+	resultChan chan numeric.Value
+	errorChan  chan error
+}
+
+// пул воркеров: ComputingPower воркеров забирают операции из общей очереди,
+// поэтому одновременно вычисляется не больше ComputingPower операций
+type workerPool struct {
+	queue   chan *task
+	workers int
+	busy    atomic.Int32
+}
+
+// функция для запуска воркеров пула
+func (a *Agent) startPool() {
+	a.pool = &workerPool{
+		queue:   make(chan *task, a.queueSize()),
+		workers: max(a.Config.ComputingPower, 1),
+	}
+	for range a.pool.workers {
+		go a.work()
+	}
+}
+
+func (a *Agent) queueSize() int {
+	if a.Config.QueueSize > 0 {
+		return a.Config.QueueSize
+	}
+	return defaultQueueSize
+}
+
+// цикл воркера: вычисление операций из очереди по одной
+func (a *Agent) work() {
+	for t := range a.pool.queue {
+		a.pool.busy.Add(1)
+		a.Worker(t.ctx, t.in, t.resultChan, t.errorChan)
+		a.pool.busy.Add(-1)
+	}
+}
+
+// функция для постановки операции в очередь. если очередь заполнена,
+// операция не принимается (ErrAgentBusy)
+func (p *workerPool) submit(t *task) error {
+	select {
+	case p.queue <- t:
+		return nil
+	default:
+		return models.ErrAgentBusy
+	}
+}
+
+// загрузка агента: число воркеров, занятых воркеров и операций в очереди
+func (a *Agent) Load() models.AgentLoad {
+	return models.AgentLoad{
+		Workers:     a.pool.workers,
+		BusyWorkers: int(a.pool.busy.Load()),
+		QueueDepth:  len(a.pool.queue),
+		QueueSize:   cap(a.pool.queue),
+	}
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/agent/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// операция, не поместившаяся в очередь, отклоняется с кодом RESOURCE_EXHAUSTED,
+// а загрузка агента показывает занятых воркеров и глубину очереди
+func TestAgent_Calculation_QueueFull(t *testing.T) {
+	cfg := &config.Config{
+		ComputingPower: 1,
+		OperationTimes: map[string]int{"+": 100},
+		QueueSize:      1,
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+	assert.Equal(t, models.AgentLoad{Workers: 1, QueueSize: 1}, agent.Load())
+
+	errs := make(chan error, 2)
+	calculate := func() {
+		_, err := agent.Calculation(context.Background(), pb.NewTaskRequest("+", 1, 2))
+		errs <- err
+	}
+
+	// первая операция занимает воркер, вторая ждет в очереди
+	go calculate()
+	assert.Eventually(t, func() bool { return agent.Load().BusyWorkers == 1 }, time.Second, time.Millisecond)
+	go calculate()
+	assert.Eventually(t, func() bool { return agent.Load().QueueDepth == 1 }, time.Second, time.Millisecond)
+
+	_, err := agent.Calculation(context.Background(), pb.NewTaskRequest("+", 1, 2))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, err.Error(), models.ErrAgentBusy.Error())
+
+	assert.NoError(t, <-errs)
+	assert.NoError(t, <-errs)
+	assert.Eventually(t, func() bool {
+		return agent.Load() == models.AgentLoad{Workers: 1, QueueSize: 1}
+	}, time.Second, time.Millisecond)
+}
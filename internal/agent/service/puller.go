@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/ArtemiySps/calc_go_final/internal/agent/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+)
+
+// запуск агента в режиме pull: агент подключается к оркестратору,
+// сам забирает задачи из очереди и возвращает результаты
+func RunPuller(cfg *config.Config, logger *zap.Logger) error {
+	addr := fmt.Sprintf("%s:%s", cfg.OrkestratorHost, cfg.OrkestratorGRPCPort)
+	logger.Info("Agent is pulling tasks from orkestrator with address: " + addr)
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return models.ErrConnectingGRPC
+	}
+	defer conn.Close()
+
+	ctx := context.Background()
+	client := pb.NewCalcServiceClient(conn)
+	a := NewAgent(cfg, logger)
+
+	// задачи, выданные агенту, отслеживаются по его ID, поэтому сначала нужна регистрация
+	interval, err := a.Announce(ctx, client, "")
+	if err != nil {
+		return err
+	}
+	go a.SendHeartbeats(ctx, client, "", interval)
+
+	return a.Pull(ctx, client)
+}
+
+// получение и вычисление задач: каждый воркер забирает задачи независимо,
+// поэтому агент вычисляет до ComputingPower задач одновременно. завершается при отмене ctx
+func (a *Agent) Pull(ctx context.Context, client pb.CalcServiceClient) error {
+	var wg sync.WaitGroup
+	for range a.pool.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.pull(ctx, client)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// цикл получения и вычисления задач. если задач нет или оркестратор недоступен,
+// воркер ждет PollInterval мс
+func (a *Agent) pull(ctx context.Context, client pb.CalcServiceClient) {
+	interval := time.Duration(a.Config.PollInterval) * time.Millisecond
+
+	for {
+		t, err := client.GetTask(ctx, &pb.GetTaskRequest{AgentId: a.ID()})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if status.Code(err) != codes.NotFound {
+				a.log.Error(err.Error())
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			continue
+		}
+
+		a.log.Info(t.Id + ": got task from orkestrator")
+		res := &pb.TaskResult{Id: t.Id, AgentId: a.ID()}
+		resp, err := a.Calculation(ctx, t.Request())
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.SetResult(resp.Result())
+			res.ResExact = resp.ResExact
+			res.ResImag = resp.ResImag
+		}
+
+		if _, err := client.SubmitResult(ctx, res); err != nil {
+			a.log.Error(t.Id + ": " + err.Error())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/agent/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// оркестратор с очередью задач: выдает задачи по порядку, собирает результаты
+// и отменяет ctx, когда получены результаты всех задач
+type queueClient struct {
+	pb.CalcServiceClient
+
+	mu      sync.Mutex
+	tasks   []*pb.Task
+	results map[string]*pb.TaskResult
+	polls   int
+	cancel  context.CancelFunc
+	want    int
+}
+
+func (c *queueClient) GetTask(ctx context.Context, in *pb.GetTaskRequest, opts ...grpc.CallOption) (*pb.Task, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.polls++
+	if len(c.tasks) == 0 {
+		return nil, status.Error(codes.NotFound, "no tasks")
+	}
+	t := c.tasks[0]
+	c.tasks = c.tasks[1:]
+	return t, nil
+}
+
+func (c *queueClient) SubmitResult(ctx context.Context, in *pb.TaskResult, opts ...grpc.CallOption) (*pb.SubmitResultResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results[in.Id] = in
+	if len(c.results) == c.want {
+		c.cancel()
+	}
+	return &pb.SubmitResultResponse{}, nil
+}
+
+// тесты для Pull
+func TestAgent_Pull(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := &queueClient{
+		tasks: []*pb.Task{
+			pb.NewTask("1", "+", 16777217, 1),
+			// задача от старого оркестратора: аргументы только во float-полях
+			{Id: "2", Opr: "*", Arg1: 4, Arg2: 5},
+			// задача в режиме decimal: аргументы в точной записи
+			{Id: "3", Opr: "/", Mode: models.ModeDecimal, Arg1Exact: "1", Arg2Exact: "8", Scale: 2},
+			pb.NewTask("4", "%", 1, 0),
+		},
+		results: make(map[string]*pb.TaskResult),
+		cancel:  cancel,
+		want:    4,
+	}
+
+	cfg := &config.Config{
+		ComputingPower: 1,
+		OperationTimes: map[string]int{"+": 0, "*": 0, "/": 0, "%": 0},
+		PollInterval:   1,
+	}
+	agent := NewAgent(cfg, zap.NewNop())
+
+	err := agent.Pull(ctx, client)
+	assert.NoError(t, err)
+
+	assert.Len(t, client.results, 4)
+	assert.Equal(t, 16777218.0, client.results["1"].Result())
+	assert.Empty(t, client.results["1"].Error)
+	assert.Equal(t, 20.0, client.results["2"].Result())
+	assert.Equal(t, float32(20), client.results["2"].Res)
+	// 0.125 с двумя знаками после запятой, половина - к четному
+	assert.Equal(t, "0.12", client.results["3"].ResExact)
+	assert.Equal(t, 0.12, client.results["3"].Result())
+	assert.Equal(t, models.ErrModuloByZero.Error(), client.results["4"].Error)
+}
+
+// если задач нет, агент продолжает опрашивать оркестратор до отмены ctx
+func TestAgent_Pull_NoTasks(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client := &queueClient{results: make(map[string]*pb.TaskResult)}
+	agent := NewAgent(&config.Config{PollInterval: 5}, zap.NewNop())
+
+	err := agent.Pull(ctx, client)
+	assert.NoError(t, err)
+	assert.Greater(t, client.polls, 1)
+	assert.Empty(t, client.results)
+}
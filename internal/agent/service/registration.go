@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+)
+
+// версия агента, сообщается оркестратору при регистрации
+const Version = "1.2.0"
+
+// операции, которые умеет вычислять агент (см. Worker)
+var Operations = []string{
+	"+", "-", "*", "/", "^", "%", "//",
+	"sqrt", "sin", "cos", "log", "abs", "round", "min", "max",
+}
+
+// ID, выданный оркестратором при регистрации (пусто, если агент не зарегистрирован)
+func (a *Agent) ID() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.id
+}
+
+// функция для регистрации у оркестратора. addr - адрес gRPC сервера агента
+// (пусто для агента, который сам забирает задачи). при ошибке попытка повторяется
+// через PollInterval мс. возвращает интервал отправки Heartbeat
+func (a *Agent) Announce(ctx context.Context, client pb.CalcServiceClient, addr string) (time.Duration, error) {
+	retry := time.Duration(a.Config.PollInterval) * time.Millisecond
+
+	for {
+		resp, err := client.Register(ctx, &pb.RegisterRequest{
+			Address:        addr,
+			ComputingPower: int32(a.Config.ComputingPower),
+			Operations:     Operations,
+			Version:        Version,
+		})
+		if err == nil {
+			a.mu.Lock()
+			a.id = resp.AgentId
+			a.mu.Unlock()
+
+			a.log.Info("agent registered with id " + resp.AgentId)
+			interval := time.Duration(resp.HeartbeatIntervalMs) * time.Millisecond
+			if interval <= 0 {
+				interval = time.Second
+			}
+			return interval, nil
+		}
+		a.log.Error("registration failed: " + err.Error())
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+}
+
+// цикл отправки Heartbeat с текущей загрузкой агента. если оркестратор не знает агента (NOT_FOUND),
+// агент регистрируется заново. завершается при отмене ctx
+func (a *Agent) SendHeartbeats(ctx context.Context, client pb.CalcServiceClient, addr string, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		load := a.Load()
+		_, err := client.Heartbeat(ctx, &pb.HeartbeatRequest{
+			AgentId:     a.ID(),
+			Workers:     int32(load.Workers),
+			BusyWorkers: int32(load.BusyWorkers),
+			QueueDepth:  int32(load.QueueDepth),
+			QueueSize:   int32(load.QueueSize),
+		})
+		if err == nil {
+			continue
+		}
+		if status.Code(err) != codes.NotFound {
+			a.log.Error("heartbeat failed: " + err.Error())
+			continue
+		}
+
+		a.log.Warn("agent is unknown to orkestrator, registering again")
+		interval, err = a.Announce(ctx, client, addr)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// регистрация и отправка Heartbeat в фоне
+func (a *Agent) keepRegistered(ctx context.Context, client pb.CalcServiceClient, addr string) {
+	interval, err := a.Announce(ctx, client, addr)
+	if err != nil {
+		return
+	}
+	a.SendHeartbeats(ctx, client, addr, interval)
+}
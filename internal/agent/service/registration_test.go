@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/agent/config"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// оркестратор, который один раз отклоняет регистрацию и один раз не узнает агента в Heartbeat
+type registryClient struct {
+	pb.CalcServiceClient
+
+	mu         sync.Mutex
+	registers  []*pb.RegisterRequest
+	heartbeats []string
+	cancel     context.CancelFunc
+}
+
+func (c *registryClient) Register(ctx context.Context, in *pb.RegisterRequest, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.registers = append(c.registers, in)
+	switch len(c.registers) {
+	case 1:
+		return nil, status.Error(codes.Unavailable, "orkestrator is not started")
+	case 2:
+		return &pb.RegisterResponse{AgentId: "first", HeartbeatIntervalMs: 1}, nil
+	default:
+		return &pb.RegisterResponse{AgentId: "second", HeartbeatIntervalMs: 1}, nil
+	}
+}
+
+func (c *registryClient) Heartbeat(ctx context.Context, in *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.heartbeats = append(c.heartbeats, in.AgentId)
+	if in.AgentId == "first" {
+		return nil, status.Error(codes.NotFound, "agent is not registered")
+	}
+	if len(c.heartbeats) == 3 {
+		c.cancel()
+	}
+	return &pb.HeartbeatResponse{}, nil
+}
+
+// тесты для регистрации и Heartbeat
+func TestAgent_Registration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := &registryClient{cancel: cancel}
+	agent := NewAgent(&config.Config{ComputingPower: 4, PollInterval: 1}, zap.NewNop())
+
+	agent.keepRegistered(ctx, client, "localhost:8080")
+
+	assert.Len(t, client.registers, 3)
+	assert.Equal(t, "localhost:8080", client.registers[0].Address)
+	assert.Equal(t, int32(4), client.registers[0].ComputingPower)
+	assert.Equal(t, Operations, client.registers[0].Operations)
+	assert.Equal(t, Version, client.registers[0].Version)
+
+	// после NOT_FOUND агент регистрируется заново и продолжает с новым ID
+	assert.Equal(t, []string{"first", "second", "second"}, client.heartbeats)
+	assert.Equal(t, "second", agent.ID())
+}
@@ -1,20 +1,71 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc/codes"
 )
 
 type Config struct {
 	OperationTimes map[string]int
 
-	OrkestratorPort string
+	OrkestratorPort     string
+	OrkestratorGRPCPort string
 
-	AgentPort string
-	AgentHost string
+	// push - оркестратор отправляет операции агенту сам (Calculation),
+	// pull - операции попадают в очередь, агенты забирают их через GetTask/SubmitResult
+	DispatchMode string
+
+	// адреса агентов (host:port) для режима push. агенты также добавляются в пул,
+	// когда регистрируются у оркестратора (Register)
+	AgentAddrs []string
+	// после стольких неудачных вызовов подряд агент исключается из пула на AgentEjectTime мс
+	AgentMaxFailures int
+	AgentEjectTime   int
+
+	// как часто агенты присылают Heartbeat (мс) и после скольких пропущенных подряд агент считается потерянным
+	HeartbeatInterval   int
+	MaxMissedHeartbeats int
+
+	// режим вычисления по умолчанию (float, decimal, rational, complex), если он не указан в запросе.
+	// в режиме decimal результат деления округляется до DecimalScale знаков после
+	// запятой способом DecimalRounding (см. models.Round*)
+	NumericMode     string
+	DecimalScale    int
+	DecimalRounding string
+
+	// на операцию отводится ее время из OperationTimes плюс OperationSlack мс,
+	// на все выражение - ExpressionTimeout мс. 0 - без ограничения
+	OperationSlack    int
+	ExpressionTimeout int
+	// в режиме push вызов агента ограничен и на стороне оркестратора: сроком операции плюс
+	// AgentQueueTimeout мс на ожидание в очереди агента. 0 - без ограничения
+	AgentQueueTimeout int
+
+	// повтор вызова агента: не больше RetryMaxAttempts попыток, пауза перед повтором
+	// растет вдвое от RetryBaseDelay до RetryMaxDelay мс со случайным разбросом.
+	// повторяются только ошибки с кодами из RetryCodes
+	RetryMaxAttempts int
+	RetryBaseDelay   int
+	RetryMaxDelay    int
+	RetryCodes       []codes.Code
+
+	// кэш результатов операций: не больше CacheSize записей (0 - кэш выключен),
+	// запись хранится CacheTTL мс (0 - без ограничения)
+	CacheSize int
+	CacheTTL  int
+
+	// операции, время которых из OperationTimes меньше LocalCostThreshold мс (0 - ни одна),
+	// оркестратор вычисляет сам. с LocalFallback он вычисляет сам и операции,
+	// для которых нет доступных агентов
+	LocalCostThreshold int
+	LocalFallback      bool
 
 	UsersDBPath string
 }
@@ -42,18 +93,191 @@ func NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, models.ErrDivisionTime
 	}
+	operationTimes["^"], err = strconv.Atoi(os.Getenv("TIME_POWER_MS"))
+	if err != nil {
+		return nil, models.ErrPowerTime
+	}
+	operationTimes["%"], err = strconv.Atoi(os.Getenv("TIME_MODULO_MS"))
+	if err != nil {
+		return nil, models.ErrModuloTime
+	}
+	operationTimes["//"], err = strconv.Atoi(os.Getenv("TIME_INT_DIVISION_MS"))
+	if err != nil {
+		return nil, models.ErrIntDivisionTime
+	}
+	for fn, env := range models.FunctionTimeEnvs {
+		operationTimes[fn], err = strconv.Atoi(os.Getenv(env))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", models.ErrFunctionTime, env)
+		}
+	}
 
 	port := os.Getenv("PORT_ORKESTRATOR")
-	agentPort := os.Getenv("PORT_AGENT")
-	agentHost := os.Getenv("HOST_AGENT")
+	grpcPort := os.Getenv("PORT_ORKESTRATOR_GRPC")
+
+	dispatchMode := os.Getenv("DISPATCH_MODE")
+	if dispatchMode == "" {
+		dispatchMode = models.DispatchPush
+	}
+	if dispatchMode != models.DispatchPush && dispatchMode != models.DispatchPull {
+		return nil, models.ErrDispatchMode
+	}
+
+	// список агентов через запятую. может быть пустым: агенты регистрируются сами
+	var agentAddrs []string
+	for _, addr := range strings.Split(os.Getenv("AGENT_ADDRS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			agentAddrs = append(agentAddrs, addr)
+		}
+	}
+	agentMaxFailures, _ := strconv.Atoi(os.Getenv("AGENT_MAX_FAILURES"))
+	agentEjectTime, _ := strconv.Atoi(os.Getenv("AGENT_EJECT_MS"))
+
+	heartbeatInterval, err := strconv.Atoi(os.Getenv("HEARTBEAT_INTERVAL_MS"))
+	if err != nil || heartbeatInterval <= 0 {
+		heartbeatInterval = 1000
+	}
+	maxMissedHeartbeats, err := strconv.Atoi(os.Getenv("MAX_MISSED_HEARTBEATS"))
+	if err != nil || maxMissedHeartbeats <= 0 {
+		maxMissedHeartbeats = 3
+	}
+
+	numericMode := os.Getenv("NUMERIC_MODE")
+	if numericMode == "" {
+		numericMode = models.ModeFloat
+	}
+	if !numeric.ValidMode(numericMode) {
+		return nil, models.ErrNumericModeEnv
+	}
+	decimalScale := 20
+	if env := os.Getenv("DECIMAL_SCALE"); env != "" {
+		decimalScale, err = strconv.Atoi(env)
+		if err != nil || decimalScale < 0 {
+			return nil, models.ErrDecimalScale
+		}
+	}
+	decimalRounding := os.Getenv("DECIMAL_ROUNDING")
+	if decimalRounding == "" {
+		decimalRounding = models.RoundHalfEven
+	}
+	if !numeric.ValidRounding(decimalRounding) {
+		return nil, models.ErrDecimalRounding
+	}
+
+	operationSlack, err := timeoutEnv("OPERATION_TIMEOUT_SLACK_MS", 1000)
+	if err != nil {
+		return nil, err
+	}
+	expressionTimeout, err := timeoutEnv("EXPRESSION_TIMEOUT_MS", 60000)
+	if err != nil {
+		return nil, err
+	}
+	agentQueueTimeout, err := timeoutEnv("AGENT_QUEUE_TIMEOUT_MS", 30000)
+	if err != nil {
+		return nil, err
+	}
+
+	retryMaxAttempts := 3
+	if env := os.Getenv("RETRY_MAX_ATTEMPTS"); env != "" {
+		retryMaxAttempts, err = strconv.Atoi(env)
+		if err != nil || retryMaxAttempts < 1 {
+			return nil, fmt.Errorf("%w: RETRY_MAX_ATTEMPTS", models.ErrRetryEnv)
+		}
+	}
+	retryBaseDelay, err := timeoutEnv("RETRY_BASE_DELAY_MS", 100)
+	if err != nil {
+		return nil, err
+	}
+	retryMaxDelay, err := timeoutEnv("RETRY_MAX_DELAY_MS", 2000)
+	if err != nil {
+		return nil, err
+	}
+	retryCodes, err := parseCodes(os.Getenv("RETRY_CODES"), "UNAVAILABLE,RESOURCE_EXHAUSTED")
+	if err != nil {
+		return nil, err
+	}
+
+	cacheSize := 1000
+	if env := os.Getenv("CACHE_SIZE"); env != "" {
+		cacheSize, err = strconv.Atoi(env)
+		if err != nil || cacheSize < 0 {
+			return nil, fmt.Errorf("%w: CACHE_SIZE", models.ErrCacheEnv)
+		}
+	}
+	cacheTTL, err := timeoutEnv("CACHE_TTL_MS", 60000)
+	if err != nil {
+		return nil, err
+	}
+
+	localCostThreshold, err := timeoutEnv("LOCAL_COST_THRESHOLD_MS", 0)
+	if err != nil {
+		return nil, err
+	}
+	localFallback := false
+	if env := os.Getenv("LOCAL_FALLBACK"); env != "" {
+		localFallback, err = strconv.ParseBool(env)
+		if err != nil {
+			return nil, fmt.Errorf("%w: LOCAL_FALLBACK", models.ErrLocalEnv)
+		}
+	}
 
 	cfg := &Config{
-		OperationTimes:  operationTimes,
-		OrkestratorPort: port,
-		AgentPort:       agentPort,
-		AgentHost:       agentHost,
-		UsersDBPath:     "./db/store.db",
+		OperationTimes:      operationTimes,
+		OrkestratorPort:     port,
+		OrkestratorGRPCPort: grpcPort,
+		DispatchMode:        dispatchMode,
+		AgentAddrs:          agentAddrs,
+		AgentMaxFailures:    agentMaxFailures,
+		AgentEjectTime:      agentEjectTime,
+		HeartbeatInterval:   heartbeatInterval,
+		MaxMissedHeartbeats: maxMissedHeartbeats,
+		NumericMode:         numericMode,
+		DecimalScale:        decimalScale,
+		DecimalRounding:     decimalRounding,
+		OperationSlack:      operationSlack,
+		ExpressionTimeout:   expressionTimeout,
+		AgentQueueTimeout:   agentQueueTimeout,
+		RetryMaxAttempts:    retryMaxAttempts,
+		RetryBaseDelay:      retryBaseDelay,
+		RetryMaxDelay:       retryMaxDelay,
+		RetryCodes:          retryCodes,
+		CacheSize:           cacheSize,
+		CacheTTL:            cacheTTL,
+		LocalCostThreshold:  localCostThreshold,
+		LocalFallback:       localFallback,
+		UsersDBPath:         "./db/store.db",
 	}
 
 	return cfg, nil
 }
+
+// время ожидания (мс) из переменной среды. пустая переменная - значение по умолчанию
+func timeoutEnv(env string, def int) (int, error) {
+	value := os.Getenv(env)
+	if value == "" {
+		return def, nil
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms < 0 {
+		return 0, fmt.Errorf("%w: %s", models.ErrTimeoutEnv, env)
+	}
+	return ms, nil
+}
+
+// коды gRPC через запятую (например, UNAVAILABLE,RESOURCE_EXHAUSTED). пустая строка - значение по умолчанию
+func parseCodes(env string, def string) ([]codes.Code, error) {
+	if strings.TrimSpace(env) == "" {
+		env = def
+	}
+
+	var list []codes.Code
+	for _, name := range strings.Split(env, ",") {
+		var c codes.Code
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if err := c.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			return nil, fmt.Errorf("%w: RETRY_CODES", models.ErrRetryEnv)
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// хендлер для получения агентов и их загрузки (воркеры и очередь). доступен по ручке "/api/v1/agents"
+func (t *TransportHttp) AgentsHandler(w http.ResponseWriter, r *http.Request) {
+	response := struct {
+		Agents []models.Agent `json:"agents"`
+	}{
+		Agents: t.s.Agents(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// хендлер для получения статистики кэша результатов операций (попадания, промахи,
+// число записей). доступен по ручке "/api/v1/cache"
+func (t *TransportHttp) CacheHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.s.CacheStats())
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/ArtemiySps/calc_go_final/pkg/expr"
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,9 +19,14 @@ type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) ExpressionOperations(expr string, user string) (float64, error) {
-	args := m.Called(expr, user)
-	return args.Get(0).(float64), args.Error(1)
+func (m *MockService) ExpressionOperations(req models.CalcRequest, user string) (models.Expression, error) {
+	args := m.Called(req, user)
+	return args.Get(0).(models.Expression), args.Error(1)
+}
+
+func (m *MockService) SubmitExpression(req models.CalcRequest, user string) (string, error) {
+	args := m.Called(req, user)
+	return args.String(0), args.Error(1)
 }
 
 func (m *MockService) GetAllExpressions(user string) (map[string]models.Expression, error) {
@@ -32,11 +39,31 @@ func (m *MockService) GetExpression(id string, user string) (models.Expression,
 	return args.Get(0).(models.Expression), args.Error(1)
 }
 
+func (m *MockService) CancelExpression(id string, user string) (models.Expression, error) {
+	args := m.Called(id, user)
+	return args.Get(0).(models.Expression), args.Error(1)
+}
+
 func (m *MockService) Clear(user string) (int64, error) {
 	args := m.Called(user)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockService) SetVariable(user string, name string, value float64) error {
+	args := m.Called(user, name, value)
+	return args.Error(0)
+}
+
+func (m *MockService) GetVariables(user string) (map[string]float64, error) {
+	args := m.Called(user)
+	return args.Get(0).(map[string]float64), args.Error(1)
+}
+
+func (m *MockService) DeleteVariable(user string, name string) error {
+	args := m.Called(user, name)
+	return args.Error(0)
+}
+
 func (m *MockService) Register(login string, password string) error {
 	args := m.Called(login, password)
 	return args.Error(0)
@@ -52,6 +79,16 @@ func (m *MockService) GetLogin(token string) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockService) Agents() []models.Agent {
+	args := m.Called()
+	return args.Get(0).([]models.Agent)
+}
+
+func (m *MockService) CacheStats() models.CacheStats {
+	args := m.Called()
+	return args.Get(0).(models.CacheStats)
+}
+
 func TestRegisterHandler(t *testing.T) {
 	mockService := new(MockService)
 
@@ -221,6 +258,18 @@ func TestOrkestratorHandler(t *testing.T) {
 			expectedStatus: http.StatusCreated,
 			expectedResult: 4,
 		},
+		{
+			name: "Syntax error",
+			payload: map[string]string{
+				"expression": "1+2+a",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      &expr.ParseError{Offset: 4, Length: 1, Token: `"a"`, Err: models.ErrUnexpectedSymbol},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
 		{
 			name: "Invalid expression",
 			payload: map[string]string{
@@ -233,16 +282,112 @@ func TestOrkestratorHandler(t *testing.T) {
 			expectedStatus: http.StatusUnprocessableEntity,
 			expectedResult: 0,
 		},
+		{
+			name: "Division by zero",
+			payload: map[string]string{
+				"expression": "1/0",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrDivisionByZero,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
+		{
+			name: "Modulo by zero",
+			payload: map[string]string{
+				"expression": "1%0",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrModuloByZero,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
+		{
+			name: "Bad power",
+			payload: map[string]string{
+				"expression": "(-8)^0.5",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrBadPower,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
+		{
+			name: "Square root of negative",
+			payload: map[string]string{
+				"expression": "sqrt(-1)",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrSqrtOfNegative,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
+		{
+			name: "Logarithm of non-positive",
+			payload: map[string]string{
+				"expression": "log(0)",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrLogOfNonPositive,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
+		{
+			name: "Result out of range",
+			payload: map[string]string{
+				"expression": "1e308*10",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrOverflow,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
+		{
+			name: "Bad variable name",
+			payload: map[string]string{
+				"expression": "x+1",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrBadVariableName,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedResult: 0,
+		},
+		{
+			name: "Expression timeout",
+			payload: map[string]string{
+				"expression": "1+1",
+			},
+			token:          "valid.token",
+			mockLogin:      "testuser",
+			mockResult:     0,
+			mockError:      models.ErrExpressionTimeout,
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedResult: 0,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockService.On("GetLogin", tt.token).Return(tt.mockLogin, nil)
-			mockService.On("ExpressionOperations", tt.payload["expression"], tt.mockLogin).
-				Return(tt.mockResult, tt.mockError)
+			mockService.On("ExpressionOperations", models.CalcRequest{Expression: tt.payload["expression"]}, tt.mockLogin).
+				Return(models.Expression{Result: tt.mockResult}, tt.mockError)
 
 			body, _ := json.Marshal(tt.payload)
-			req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBuffer(body))
+			req := httptest.NewRequest("POST", "/api/v1/calculate?sync=true", bytes.NewBuffer(body))
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", tt.token)
 
@@ -265,3 +410,441 @@ func TestOrkestratorHandler(t *testing.T) {
 		})
 	}
 }
+
+// ошибка разбора возвращается в JSON с позицией
+func TestOrkestratorHandler_ParseErrorJSON(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:    mockService,
+		log:  zap.NewNop(),
+		port: "8080",
+	}
+
+	_, parseErr := expr.Parse("1+--2")
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+	mockService.On("ExpressionOperations", models.CalcRequest{Expression: "1+--2"}, "testuser").Return(models.Expression{}, parseErr)
+
+	body, _ := json.Marshal(map[string]string{"expression": "1+--2"})
+	req := httptest.NewRequest("POST", "/api/v1/calculate?sync=true", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "valid.token")
+	rr := httptest.NewRecorder()
+
+	transport.OrkestratorHandler(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response struct {
+		Error struct {
+			Error    string   `json:"error"`
+			Offset   int      `json:"offset"`
+			Length   int      `json:"length"`
+			Token    string   `json:"token"`
+			Expected []string `json:"expected"`
+		} `json:"error"`
+		Snippet string `json:"snippet"`
+	}
+	err := json.NewDecoder(rr.Body).Decode(&response)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ErrBadExpression.Error(), response.Error.Error)
+	assert.Equal(t, 3, response.Error.Offset)
+	assert.Equal(t, 1, response.Error.Length)
+	assert.Equal(t, `"-"`, response.Error.Token)
+	assert.Equal(t, []string{"number", "identifier", "reference", `"("`}, response.Error.Expected)
+	assert.Equal(t, "1+--2\n   ^", response.Snippet)
+}
+
+// в табличном формате под таблицей выводится место синтаксической ошибки
+func TestWriteTable_ParseError(t *testing.T) {
+	table := writeTable(map[string]models.Expression{
+		"1": {ID: "1", Expr: "1+2+$", Status: models.StatusFailed, Error: "unexpected symbol"},
+	})
+
+	assert.Contains(t, table, "1: unexpected symbol at position 4: unexpected \"$\"\n1+2+$\n    ^\n")
+}
+
+// результат выводится только у вычисленных выражений, у отмененных и прерванных по таймауту - ошибка
+func TestWriteTable_Status(t *testing.T) {
+	table := writeTable(map[string]models.Expression{
+		"1": {ID: "1", Expr: "1+1", Status: models.StatusCompleted, Result: 2},
+		"2": {ID: "2", Expr: "2+2", Status: models.StatusCancelled, Error: "expression was cancelled"},
+		"3": {ID: "3", Expr: "3+3", Status: models.StatusTimeout, Error: "expression timed out"},
+		"4": {ID: "4", Expr: "4+4", Status: models.StatusPending},
+	})
+
+	rows := make(map[string][]string)
+	for _, line := range strings.Split(table, "\n")[1:] {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			rows[fields[0]] = fields
+		}
+	}
+	assert.Equal(t, []string{"1", "1+1", "none", models.StatusCompleted, "2.00", "none"}, rows["1"])
+	assert.Equal(t, []string{"2", "2+2", "none", models.StatusCancelled, "none", "expression", "was", "cancelled"}, rows["2"])
+	assert.Equal(t, []string{"3", "3+3", "none", models.StatusTimeout, "none", "expression", "timed", "out"}, rows["3"])
+	assert.Equal(t, []string{"4", "4+4", "none", models.StatusPending, "none"}, rows["4"])
+}
+
+// переменные из запроса передаются в сервис
+func TestOrkestratorHandler_Variables(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:    mockService,
+		log:  zap.NewNop(),
+		port: "8080",
+	}
+
+	request := models.CalcRequest{
+		Expression: "a*x+b",
+		Variables:  map[string]float64{"a": 2, "x": 3, "b": 1},
+	}
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+	mockService.On("ExpressionOperations", request, "testuser").Return(models.Expression{Result: 7}, nil)
+
+	body := []byte(`{"expression":"a*x+b","variables":{"a":2,"x":3,"b":1}}`)
+	req := httptest.NewRequest("POST", "/api/v1/calculate?sync=true", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "valid.token")
+	rr := httptest.NewRecorder()
+
+	transport.OrkestratorHandler(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+// в режиме decimal кроме приближения возвращается точная запись результата
+func TestOrkestratorHandler_Decimal(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:    mockService,
+		log:  zap.NewNop(),
+		port: "8080",
+	}
+
+	request := models.CalcRequest{Expression: "0.1+0.2", Mode: models.ModeDecimal}
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+	mockService.On("ExpressionOperations", request, "testuser").Return(models.Expression{Mode: models.ModeDecimal, Result: 0.3, Value: "0.3"}, nil)
+
+	body := []byte(`{"expression":"0.1+0.2","mode":"decimal"}`)
+	req := httptest.NewRequest("POST", "/api/v1/calculate?sync=true", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "valid.token")
+	rr := httptest.NewRecorder()
+
+	transport.OrkestratorHandler(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var response struct {
+		Result float64 `json:"result"`
+		Value  string  `json:"value"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, 0.3, response.Result)
+	assert.Equal(t, "0.3", response.Value)
+	mockService.AssertExpectations(t)
+}
+
+// в режиме rational возвращаются числитель, знаменатель и десятичное приближение
+func TestOrkestratorHandler_Rational(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:    mockService,
+		log:  zap.NewNop(),
+		port: "8080",
+	}
+
+	request := models.CalcRequest{Expression: "1/3+1/6", Mode: models.ModeRational}
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+	mockService.On("ExpressionOperations", request, "testuser").Return(models.Expression{
+		Mode:     models.ModeRational,
+		Result:   0.5,
+		Value:    "1/2",
+		Fraction: &models.Fraction{Numerator: "1", Denominator: "2"},
+		Decimal:  "0.5",
+	}, nil)
+
+	body := []byte(`{"expression":"1/3+1/6","mode":"rational"}`)
+	req := httptest.NewRequest("POST", "/api/v1/calculate?sync=true", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "valid.token")
+	rr := httptest.NewRecorder()
+
+	transport.OrkestratorHandler(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"result":0.5,"value":"1/2","fraction":{"numerator":"1","denominator":"2"},"decimal":"0.5"}`, rr.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+// в режиме complex возвращаются действительная и мнимая части
+func TestOrkestratorHandler_Complex(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:    mockService,
+		log:  zap.NewNop(),
+		port: "8080",
+	}
+
+	request := models.CalcRequest{Expression: "(2+3i)*(1-i)", Mode: models.ModeComplex}
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+	mockService.On("ExpressionOperations", request, "testuser").Return(models.Expression{
+		Mode:    models.ModeComplex,
+		Result:  5,
+		Value:   "5+1i",
+		Complex: &models.Complex{Real: 5, Imag: 1},
+	}, nil)
+
+	body := []byte(`{"expression":"(2+3i)*(1-i)","mode":"complex"}`)
+	req := httptest.NewRequest("POST", "/api/v1/calculate?sync=true", bytes.NewBuffer(body))
+	req.Header.Set("Authorization", "valid.token")
+	rr := httptest.NewRecorder()
+
+	transport.OrkestratorHandler(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.JSONEq(t, `{"result":5,"value":"5+1i","complex":{"real":5,"imag":1}}`, rr.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+// в табличном формате выводятся переменные выражения
+func TestWriteTable_Variables(t *testing.T) {
+	table := writeTable(map[string]models.Expression{
+		"1": {ID: "1", Expr: "a*x+b", Variables: map[string]float64{"x": 3, "a": 2, "b": 1.5}, Status: models.StatusCompleted, Result: 7.5},
+		"2": {ID: "2", Expr: "a*y", Variables: map[string]float64{"a": 2}, Status: models.StatusFailed, Error: "undefined variable"},
+		"3": {ID: "3", Expr: "1/3", Mode: models.ModeDecimal, Status: models.StatusCompleted, Result: 0.333, Value: "0.333"},
+		"4": {ID: "4", Expr: "sqrt(-4)", Mode: models.ModeComplex, Status: models.StatusCompleted, Value: "2i", Complex: &models.Complex{Imag: 2}},
+	})
+
+	assert.Contains(t, table, "a=2, b=1.5, x=3")
+	assert.Contains(t, table, "7.50")
+	assert.Contains(t, table, "0.333 ")
+	assert.Contains(t, table, "2i ")
+	assert.Contains(t, table, "2: undefined variable at position 2: unexpected \"y\"\na*y\n  ^\n")
+}
+
+func TestVariableHandler(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:    mockService,
+		log:  zap.NewNop(),
+		port: "8080",
+	}
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		setup          func()
+		expectedStatus int
+	}{
+		{
+			name:   "Save variable",
+			method: http.MethodPut,
+			path:   "/api/v1/variables/rate",
+			body:   `{"value":1.5}`,
+			setup: func() {
+				mockService.On("SetVariable", "testuser", "rate", 1.5).Return(nil).Once()
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "Bad variable name",
+			method: http.MethodPut,
+			path:   "/api/v1/variables/1x",
+			body:   `{"value":1}`,
+			setup: func() {
+				mockService.On("SetVariable", "testuser", "1x", 1.0).Return(models.ErrBadVariableName).Once()
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Missing value",
+			method:         http.MethodPut,
+			path:           "/api/v1/variables/rate",
+			body:           `{}`,
+			setup:          func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Delete unknown variable",
+			method: http.MethodDelete,
+			path:   "/api/v1/variables/rate",
+			setup: func() {
+				mockService.On("DeleteVariable", "testuser", "rate").Return(models.ErrVariableNotFound).Once()
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodPost,
+			path:           "/api/v1/variables/rate",
+			setup:          func() {},
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "valid.token")
+			rr := httptest.NewRecorder()
+
+			transport.VariableHandler(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// по умолчанию выражение вычисляется в фоне, а ID возвращается сразу
+func TestOrkestratorHandler_Async(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:    mockService,
+		log:  zap.NewNop(),
+		port: "8080",
+	}
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+
+	tests := []struct {
+		name           string
+		expression     string
+		mockID         string
+		mockError      error
+		expectedStatus int
+	}{
+		{
+			name:           "Accepted",
+			expression:     "2+2",
+			mockID:         "123",
+			mockError:      nil,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Syntax error is reported immediately",
+			expression:     "2+",
+			mockID:         "",
+			mockError:      &expr.ParseError{Offset: 2, Token: "end of expression", Err: models.ErrBadExpression},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService.On("SubmitExpression", models.CalcRequest{Expression: tt.expression}, "testuser").
+				Return(tt.mockID, tt.mockError)
+
+			body, _ := json.Marshal(map[string]string{"expression": tt.expression})
+			req := httptest.NewRequest("POST", "/api/v1/calculate", bytes.NewBuffer(body))
+			req.Header.Set("Authorization", "valid.token")
+			rr := httptest.NewRecorder()
+
+			transport.OrkestratorHandler(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.mockError == nil {
+				var response struct {
+					ID string `json:"id"`
+				}
+				err := json.NewDecoder(rr.Body).Decode(&response)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.mockID, response.ID)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+// тесты для AgentsHandler
+func TestAgentsHandler(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:   mockService,
+		log: zap.NewNop(),
+	}
+
+	mockService.On("Agents").Return([]models.Agent{{
+		ID:             "a1",
+		ComputingPower: 2,
+		Operations:     []string{"+"},
+		Version:        "test",
+		Load:           models.AgentLoad{Workers: 2, BusyWorkers: 1, QueueDepth: 4, QueueSize: 100},
+	}})
+
+	req := httptest.NewRequest("GET", "/api/v1/agents", nil)
+	rr := httptest.NewRecorder()
+	transport.AgentsHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"agents":[{"id":"a1","computing_power":2,"operations":["+"],"version":"test",
+		"load":{"workers":2,"busy_workers":1,"queue_depth":4,"queue_size":100}}]}`, rr.Body.String())
+}
+
+func TestCacheHandler(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:   mockService,
+		log: zap.NewNop(),
+	}
+
+	mockService.On("CacheStats").Return(models.CacheStats{
+		Enabled: true, Size: 2, Capacity: 1000, TTLMs: 60000, Hits: 3, Misses: 1, HitRate: 0.75,
+	})
+
+	req := httptest.NewRequest("GET", "/api/v1/cache", nil)
+	rr := httptest.NewRecorder()
+	transport.CacheHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"enabled":true,"size":2,"capacity":1000,"ttl_ms":60000,"hits":3,"misses":1,
+		"evictions":0,"hit_rate":0.75}`, rr.Body.String())
+}
+
+// тесты для отмены вычисления выражения
+func TestExpressionHandler_Cancel(t *testing.T) {
+	mockService := new(MockService)
+	transport := &TransportHttp{
+		s:   mockService,
+		log: zap.NewNop(),
+	}
+
+	cancelled := models.Expression{ID: "1", Expr: "1+2", Status: models.StatusCancelled, Error: models.ErrCancelled.Error()}
+	mockService.On("GetLogin", "valid.token").Return("testuser", nil)
+	mockService.On("CancelExpression", "1", "testuser").Return(cancelled, nil)
+	mockService.On("CancelExpression", "2", "testuser").
+		Return(models.Expression{ID: "2", Status: models.StatusCompleted}, models.ErrNotRunning)
+	mockService.On("CancelExpression", "3", "testuser").Return(models.Expression{}, models.ErrCannotFindObject)
+	mockService.On("GetExpression", "1", "testuser").Return(cancelled, nil)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{name: "Delete", method: "DELETE", path: "/api/v1/expression/1", expectedCode: http.StatusOK},
+		{name: "Post cancel", method: "POST", path: "/api/v1/expression/1/cancel", expectedCode: http.StatusOK},
+		{name: "Get", method: "GET", path: "/api/v1/expression/1", expectedCode: http.StatusOK},
+		{name: "Already finished", method: "DELETE", path: "/api/v1/expression/2", expectedCode: http.StatusConflict},
+		{name: "Not found", method: "POST", path: "/api/v1/expression/3/cancel", expectedCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "valid.token")
+			rr := httptest.NewRecorder()
+
+			transport.ExpressionHandler(rr, req)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			if tt.expectedCode == http.StatusOK {
+				assert.Contains(t, rr.Body.String(), `"status":"cancelled"`)
+			}
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "CancelExpression", 4)
+	mockService.AssertNumberOfCalls(t, "GetExpression", 1)
+}
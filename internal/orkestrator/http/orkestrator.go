@@ -2,11 +2,15 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
+	"github.com/ArtemiySps/calc_go_final/pkg/expr"
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
 )
 
@@ -15,20 +19,27 @@ func writeTable(expressions map[string]models.Expression) string {
 
 	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
 
-	fmt.Fprintln(w, "ID\tExpression\tStatus\tResult\tError")
+	fmt.Fprintln(w, "ID\tExpression\tVariables\tStatus\tResult\tError")
 
 	for _, expr := range expressions {
 		resultStr := fmt.Sprintf("%.2f", expr.Result)
+		// в точных режимах выводится точная запись результата
+		if expr.Value != "" {
+			resultStr = expr.Value
+		}
 		resultErr := expr.Error
-		if expr.Status == models.StatusFailed || expr.Status == models.StatusPending {
+		// результат есть только у вычисленных выражений, у остальных (pending, failed,
+		// cancelled, timeout) выводится ошибка
+		if expr.Status != models.StatusCompleted {
 			resultStr = "none"
 		} else {
 			resultErr = "none"
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 			expr.ID,
 			expr.Expr,
+			formatVariables(expr.Variables),
 			expr.Status,
 			resultStr,
 			resultErr,
@@ -36,14 +47,115 @@ func writeTable(expressions map[string]models.Expression) string {
 	}
 
 	w.Flush()
+
+	// для синтаксических ошибок под таблицей выводится выражение с указанием места ошибки
+	for _, e := range expressions {
+		if e.Status != models.StatusFailed {
+			continue
+		}
+		tree, err := expr.Parse(e.Expr)
+		if err == nil {
+			_, err = expr.Bind(e.Expr, tree, e.Variables, nil)
+		}
+		var perr *expr.ParseError
+		if errors.As(err, &perr) {
+			fmt.Fprintf(&sb, "\n%s: %s\n%s\n", e.ID, perr.Error(), perr.Snippet(e.Expr))
+		}
+	}
+
 	return sb.String()
 }
 
-// хендлер для оркестратора. доступен по ручке "/api/v1/calculate"
-func (t *TransportHttp) OrkestratorHandler(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		Expression string `json:"expression"`
+// переменные выражения в виде "a=2, b=1", отсортированные по имени
+func formatVariables(vars map[string]float64) string {
+	if len(vars) == 0 {
+		return "none"
 	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+strconv.FormatFloat(vars[name], 'g', -1, 64))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ответ с ошибкой разбора выражения в формате JSON
+func writeParseError(w http.ResponseWriter, expression string, perr *expr.ParseError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":   perr,
+		"snippet": perr.Snippet(expression),
+	})
+}
+
+// ошибки в самом выражении или его аргументах (422): повтор того же запроса не поможет
+var userErrors = []error{
+	models.ErrBadExpression,
+	models.ErrUnexpectedSymbol,
+	models.ErrBadNumber,
+	models.ErrUndefinedVariable,
+	models.ErrBadVariableName,
+	models.ErrImaginaryNumber,
+	models.ErrReferenceNotFound,
+	models.ErrReferencePending,
+	models.ErrReferenceFailed,
+	models.ErrReferenceCancelled,
+	models.ErrReferenceTimeout,
+	models.ErrUnknownMode,
+	models.ErrUnsupportedOp,
+	models.ErrOverflow,
+	models.ErrDivisionByZero,
+	models.ErrModuloByZero,
+	models.ErrBadPower,
+	models.ErrSqrtOfNegative,
+	models.ErrLogOfNonPositive,
+}
+
+// ошибка в выражении или его аргументах
+func isUserError(err error) bool {
+	for _, target := range userErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// ответ с ошибкой вычисления выражения
+func (t *TransportHttp) writeCalcError(w http.ResponseWriter, expression string, err error) {
+	t.log.Error(err.Error())
+	var perr *expr.ParseError
+	switch {
+	case errors.As(err, &perr):
+		writeParseError(w, expression, perr)
+	case isUserError(err):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, models.ErrNoAgents):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, models.ErrModeNotSupported):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, models.ErrCancelled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrOperationTimeout):
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+	case errors.Is(err, models.ErrExpressionTimeout):
+		http.Error(w, err.Error(), http.StatusGatewayTimeout)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// хендлер для оркестратора. доступен по ручке "/api/v1/calculate".
+// по умолчанию сразу возвращает ID выражения (202), вычисление идет в фоне.
+// с параметром "?sync=true" ждет окончания вычисления и возвращает результат (201)
+func (t *TransportHttp) OrkestratorHandler(w http.ResponseWriter, r *http.Request) {
+	var request models.CalcRequest
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -58,23 +170,44 @@ func (t *TransportHttp) OrkestratorHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	res, err := t.s.ExpressionOperations(request.Expression, login)
-	if err != nil {
-		t.log.Error(err.Error())
-		switch err {
-		case models.ErrBadExpression:
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		case models.ErrUnexpectedSymbol:
-			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
-		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	if sync, _ := strconv.ParseBool(r.URL.Query().Get("sync")); !sync {
+		id, err := t.s.SubmitExpression(request, login)
+		if err != nil {
+			t.writeCalcError(w, request.Expression, err)
+			return
 		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": id,
+		})
 		return
 	}
 
-	data, err := json.Marshal(map[string]any{
-		"result": res,
-	})
+	res, err := t.s.ExpressionOperations(request, login)
+	if err != nil {
+		t.writeCalcError(w, request.Expression, err)
+		return
+	}
+
+	// в точных режимах кроме приближения возвращается точная запись результата,
+	// в режиме rational - еще числитель, знаменатель и десятичное приближение,
+	// в режиме complex - действительная и мнимая части
+	body := map[string]any{
+		"result": res.Result,
+	}
+	if res.Value != "" {
+		body["value"] = res.Value
+	}
+	if res.Fraction != nil {
+		body["fraction"] = res.Fraction
+		body["decimal"] = res.Decimal
+	}
+	if res.Complex != nil {
+		body["complex"] = res.Complex
+	}
+	data, err := json.Marshal(body)
 	if err != nil {
 		t.log.Error(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -161,6 +294,56 @@ func (t *TransportHttp) GetExpressionHandler(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(response)
 }
 
+// хендлер для выражения по id: DELETE "/api/v1/expression/<id>" и POST "/api/v1/expression/<id>/cancel"
+// отменяют вычисление, остальные запросы возвращают выражение
+func (t *TransportHttp) ExpressionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete || (r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/cancel")) {
+		t.CancelExpressionHandler(w, r)
+		return
+	}
+	t.GetExpressionHandler(w, r)
+}
+
+// хендлер для отмены вычисления выражения. возвращает выражение со статусом cancelled,
+// для завершенного выражения - 409
+func (t *TransportHttp) CancelExpressionHandler(w http.ResponseWriter, r *http.Request) {
+	tokenString := r.Header.Get("Authorization")
+	login, err := t.s.GetLogin(tokenString)
+	if err != nil {
+		t.log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	id := strings.TrimSuffix(r.URL.Path[len("/api/v1/expression/"):], "/cancel")
+	expression, err := t.s.CancelExpression(id, login)
+	switch {
+	case errors.Is(err, models.ErrNotRunning):
+		t.log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		t.log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if t.table {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(writeTable(map[string]models.Expression{expression.ID: expression})))
+		return
+	}
+
+	response := struct {
+		Exprs models.Expression `json:"expression"`
+	}{
+		Exprs: expression,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func (t *TransportHttp) ClearHandler(w http.ResponseWriter, r *http.Request) {
 	tokenString := r.Header.Get("Authorization")
 	login, err := t.s.GetLogin(tokenString)
@@ -10,14 +10,23 @@ import (
 )
 
 type Service interface {
-	ExpressionOperations(expr string, user string) (float64, error)
+	ExpressionOperations(req models.CalcRequest, user string) (models.Expression, error)
+	SubmitExpression(req models.CalcRequest, user string) (string, error)
 	GetAllExpressions(user string) (map[string]models.Expression, error)
 	GetExpression(id string, user string) (models.Expression, error)
+	CancelExpression(id string, user string) (models.Expression, error)
 	Clear(user string) (int64, error)
 
+	SetVariable(user string, name string, value float64) error
+	GetVariables(user string) (map[string]float64, error)
+	DeleteVariable(user string, name string) error
+
 	Register(login string, password string) error
 	Login(login string, password string) (string, error)
 	GetLogin(token string) (string, error)
+
+	Agents() []models.Agent
+	CacheStats() models.CacheStats
 }
 
 type TransportHttp struct {
@@ -42,8 +51,12 @@ func NewTransportHttp(s Service, port string, logger *zap.Logger) (*TransportHtt
 
 	http.Handle("/api/v1/calculate", AuthMiddleware(http.HandlerFunc(t.OrkestratorHandler)))
 	http.Handle("/api/v1/expressions", AuthMiddleware(http.HandlerFunc(t.GetAllExpressionsHandler)))
-	http.Handle("/api/v1/expression/", AuthMiddleware(http.HandlerFunc(t.GetExpressionHandler)))
+	http.Handle("/api/v1/expression/", AuthMiddleware(http.HandlerFunc(t.ExpressionHandler)))
 	http.Handle("/api/v1/clear", AuthMiddleware(http.HandlerFunc(t.ClearHandler)))
+	http.Handle("/api/v1/variables", AuthMiddleware(http.HandlerFunc(t.GetVariablesHandler)))
+	http.Handle("/api/v1/variables/", AuthMiddleware(http.HandlerFunc(t.VariableHandler)))
+	http.Handle("/api/v1/agents", AuthMiddleware(http.HandlerFunc(t.AgentsHandler)))
+	http.Handle("/api/v1/cache", AuthMiddleware(http.HandlerFunc(t.CacheHandler)))
 
 	http.HandleFunc("/api/v1/register", t.RegisterHandler)
 	http.HandleFunc("/api/v1/login", t.LoginHandler)
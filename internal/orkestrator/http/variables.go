@@ -0,0 +1,97 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// хендлер для получения сохраненных переменных. доступен по ручке "/api/v1/variables"
+func (t *TransportHttp) GetVariablesHandler(w http.ResponseWriter, r *http.Request) {
+	tokenString := r.Header.Get("Authorization")
+	login, err := t.s.GetLogin(tokenString)
+	if err != nil {
+		t.log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	vars, err := t.s.GetVariables(login)
+	if err != nil {
+		t.log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Vars map[string]float64 `json:"variables"`
+	}{
+		Vars: vars,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// хендлер для сохранения (PUT) и удаления (DELETE) переменной. доступен по ручке "/api/v1/variables/<name>"
+func (t *TransportHttp) VariableHandler(w http.ResponseWriter, r *http.Request) {
+	tokenString := r.Header.Get("Authorization")
+	login, err := t.s.GetLogin(tokenString)
+	if err != nil {
+		t.log.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := r.URL.Path[len("/api/v1/variables/"):]
+
+	switch r.Method {
+	case http.MethodPut:
+		var request struct {
+			Value *float64 `json:"value"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Value == nil {
+			http.Error(w, "missing value", http.StatusBadRequest)
+			return
+		}
+
+		err = t.s.SetVariable(login, name, *request.Value)
+		if err != nil {
+			t.log.Error(err.Error())
+			switch {
+			case errors.Is(err, models.ErrBadVariableName):
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		fmt.Fprintf(w, "variable %s saved\n", name)
+
+	case http.MethodDelete:
+		err := t.s.DeleteVariable(login, name)
+		if err != nil {
+			t.log.Error(err.Error())
+			switch {
+			case errors.Is(err, models.ErrVariableNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			default:
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		fmt.Fprintf(w, "variable %s deleted\n", name)
+
+	default:
+		w.Header().Set("Allow", "PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+)
+
+// запись кэша: результат операции и срок его хранения
+type cacheEntry struct {
+	key     string
+	res     numeric.Value
+	expires time.Time
+}
+
+// кэш результатов операций. ключ - режим, операция и точная запись аргументов,
+// поэтому одинаковые операции (и одинаковые подвыражения, шаги которых совпадают)
+// разных выражений вычисляются агентами один раз. при переполнении вытесняется
+// запись, которая дольше всех не использовалась. nil - кэш выключен
+type resultCache struct {
+	mu   sync.Mutex
+	size int
+	ttl  time.Duration
+	// записи в порядке использования, последняя использованная - первая
+	order *list.List
+	items map[string]*list.Element
+
+	hits, misses, evictions uint64
+
+	now func() time.Time
+}
+
+// size - наибольшее число записей (0 - кэш выключен), ttl - время хранения записи (0 - без ограничения)
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	if size <= 0 {
+		return nil
+	}
+	return &resultCache{
+		size:  size,
+		ttl:   ttl,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// ключ операции в кэше
+func cacheKey(mode string, op string, x, y numeric.Value) string {
+	return mode + " " + op + " " + x.String() + " " + y.String()
+}
+
+func (c *resultCache) get(key string) (numeric.Value, bool) {
+	if c == nil {
+		return numeric.Value{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if ok && c.expired(el.Value.(*cacheEntry)) {
+		c.remove(el)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return numeric.Value{}, false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).res, true
+}
+
+func (c *resultCache) put(key string, res numeric.Value) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = c.now().Add(c.ttl)
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value = &cacheEntry{key: key, res: res, expires: expires}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, res: res, expires: expires})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *resultCache) expired(e *cacheEntry) bool {
+	return !e.expires.IsZero() && !c.now().Before(e.expires)
+}
+
+func (c *resultCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry).key)
+}
+
+// статистика кэша: число записей, попаданий, промахов и вытеснений
+func (c *resultCache) stats() models.CacheStats {
+	if c == nil {
+		return models.CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := models.CacheStats{
+		Enabled:   true,
+		Size:      c.order.Len(),
+		Capacity:  c.size,
+		TTLMs:     int(c.ttl / time.Millisecond),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		s.HitRate = float64(c.hits) / float64(total)
+	}
+	return s
+}
+
+// статистика кэша результатов операций
+func (o *Orkestrator) CacheStats() models.CacheStats {
+	return o.cache.stats()
+}
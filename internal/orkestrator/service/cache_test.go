@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// тесты для вытеснения и срока хранения записей кэша
+func TestResultCache(t *testing.T) {
+	now := time.Now()
+	c := newResultCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.put("a", numeric.Value{Float: 1})
+	c.put("b", numeric.Value{Float: 2})
+	_, ok := c.get("a")
+	assert.True(t, ok)
+
+	// вытесняется запись, которая дольше всех не использовалась
+	c.put("c", numeric.Value{Float: 3})
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	res, ok := c.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 3.0, res.Float)
+
+	// истекшая запись не возвращается
+	now = now.Add(time.Minute)
+	_, ok = c.get("a")
+	assert.False(t, ok)
+
+	assert.Equal(t, models.CacheStats{
+		Enabled:   true,
+		Size:      1,
+		Capacity:  2,
+		TTLMs:     60000,
+		Hits:      2,
+		Misses:    2,
+		Evictions: 1,
+		HitRate:   0.5,
+	}, c.stats())
+
+	// выключенный кэш
+	off := newResultCache(0, time.Minute)
+	off.put("a", numeric.Value{Float: 1})
+	_, ok = off.get("a")
+	assert.False(t, ok)
+	assert.Equal(t, models.CacheStats{}, off.stats())
+}
+
+// в ключ входят режим и точная запись аргументов
+func TestCacheKey(t *testing.T) {
+	x, y := numeric.Value{Float: 0.1, Exact: "0.1"}, numeric.Value{Float: 0.2, Exact: "0.2"}
+	assert.Equal(t, "decimal + 0.1 0.2", cacheKey(models.ModeDecimal, "+", x, y))
+	assert.NotEqual(t, cacheKey(models.ModeDecimal, "+", x, y), cacheKey(models.ModeRational, "+", x, y))
+	assert.Equal(t, "float / 1 3", cacheKey(models.ModeFloat, "/", numeric.Value{Float: 1}, numeric.Value{Float: 3}))
+}
+
+// повторное выражение берет результаты из кэша и не вызывает агента.
+// с no_cache операции вычисляются заново
+func TestOrkestrator_Cache(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	client := &flakyClient{}
+	agents := newAgentPool(100, time.Minute, zap.NewNop())
+	agents.add("agent", client)
+	o := &Orkestrator{
+		Config: &config.Config{RetryMaxAttempts: 1},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: agents,
+		cache:  newResultCache(100, time.Minute),
+	}
+
+	tests := []struct {
+		name     string
+		req      models.CalcRequest
+		calls    int32
+		expected []models.Step
+	}{
+		{
+			name:  "First request",
+			req:   models.CalcRequest{Expression: "(1+2)+4"},
+			calls: 2,
+			expected: []models.Step{
+				{Step: 0, Operation: "+", Arg1: "1", Arg2: "2", Result: "3", Source: models.SourceAgent},
+				{Step: 1, Operation: "+", Arg1: "3", Arg2: "4", Result: "7", Source: models.SourceAgent},
+			},
+		},
+		{
+			name:  "Same subexpression",
+			req:   models.CalcRequest{Expression: "(1+2)+5"},
+			calls: 1,
+			expected: []models.Step{
+				{Step: 0, Operation: "+", Arg1: "1", Arg2: "2", Result: "3", Source: models.SourceCache},
+				{Step: 1, Operation: "+", Arg1: "3", Arg2: "5", Result: "8", Source: models.SourceAgent},
+			},
+		},
+		{
+			name:  "No cache",
+			req:   models.CalcRequest{Expression: "(1+2)+4", NoCache: true},
+			calls: 2,
+			expected: []models.Step{
+				{Step: 0, Operation: "+", Arg1: "1", Arg2: "2", Result: "3", Source: models.SourceAgent},
+				{Step: 1, Operation: "+", Arg1: "3", Arg2: "4", Result: "7", Source: models.SourceAgent},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client.calls.Store(0)
+
+			e, err := o.ExpressionOperations(tt.req, "testuser")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.calls, client.calls.Load())
+			assert.Equal(t, tt.expected, e.Steps)
+
+			saved, err := o.GetExpression(e.ID, "testuser")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, saved.Steps)
+		})
+	}
+
+	stats := o.CacheStats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, 3, stats.Size)
+}
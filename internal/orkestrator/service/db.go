@@ -3,71 +3,237 @@ package service
 import (
 	"context"
 	"database/sql"
-	"os"
+	"encoding/json"
 
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
 )
 
-// создание/открытие БД для выражений
-func ExpressionStorageOperations() (*sql.DB, error) {
-	_, err := os.Stat("expressions.db")
-	if os.IsNotExist(err) {
-		ctx := context.TODO()
+const expressionsTable = `
+CREATE TABLE IF NOT EXISTS expressions(
+	user TEXT NOT NULL,
+	id TEXT NOT NULL,
+	expr TEXT NOT NULL,
+	status TEXT NOT NULL,
+	result REAL,
+	error TEXT,
+	variables TEXT,
+	mode TEXT,
+	value TEXT,
+	attempts INTEGER DEFAULT 0,
+	plan TEXT,
+	steps TEXT,
+	no_cache INTEGER DEFAULT 0
+);`
 
-		db, err := sql.Open("sqlite3", "./db/expressions.db")
-		if err != nil {
-			return nil, err
-		}
+// столбец таблицы и его определение
+type column struct {
+	name string
+	def  string
+}
 
-		err = db.PingContext(ctx)
-		if err != nil {
-			return nil, err
-		}
+// столбцы, добавленные в таблицу выражений после первой версии. в старых БД они создаются при запуске
+var expressionsColumns = []column{
+	{name: "variables", def: "TEXT"},
+	{name: "mode", def: "TEXT"},
+	{name: "value", def: "TEXT"},
+	{name: "attempts", def: "INTEGER DEFAULT 0"},
+	{name: "plan", def: "TEXT"},
+	{name: "steps", def: "TEXT"},
+	{name: "no_cache", def: "INTEGER DEFAULT 0"},
+}
 
-		const (
-			expressionsTable = `
-		CREATE TABLE IF NOT EXISTS expressions(
-			user TEXT NOT NULL,
-			id TEXT NOT NULL,
-			expr TEXT NOT NULL,
-			status TEXT NOT NULL,
-			result REAL,
-			error TEXT
-		);`
-		)
-
-		if _, err := db.ExecContext(ctx, expressionsTable); err != nil {
-			return nil, err
+// создание таблицы выражений и недостающих в ней столбцов
+func createExpressionsTable(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, expressionsTable); err != nil {
+		return err
+	}
+	return addColumns(ctx, db, "expressions", expressionsColumns)
+}
+
+// создание недостающих столбцов таблицы
+func addColumns(ctx context.Context, db *sql.DB, table string, columns []column) error {
+	existing := make(map[string]bool)
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info('"+table+"')")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
 		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-		return db, nil
+	for _, col := range columns {
+		if existing[col.name] {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, "ALTER TABLE "+table+" ADD COLUMN "+col.name+" "+col.def); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// создание/открытие БД для выражений
+func ExpressionStorageOperations() (*sql.DB, error) {
+	ctx := context.TODO()
 
 	db, err := sql.Open("sqlite3", "./db/expressions.db")
 	if err != nil {
 		return nil, models.ErrDatabaseCreating
 	}
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := createExpressionsTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	if err := createVariablesTable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	if err := createStepsTable(ctx, db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
+// перевод переменных выражения в JSON для хранения в БД
+func encodeVariables(vars map[string]float64) (string, error) {
+	if len(vars) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func decodeSteps(data sql.NullString) ([]models.Step, error) {
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	var steps []models.Step
+	if err := json.Unmarshal([]byte(data.String), &steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+func decodeVariables(data sql.NullString) (map[string]float64, error) {
+	if !data.Valid || data.String == "" {
+		return nil, nil
+	}
+	var vars map[string]float64
+	if err := json.Unmarshal([]byte(data.String), &vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
 // добавляет выражение в БД
-func (o *Orkestrator) AddExpressionToStorage(expr string, user string) (string, error) {
+func (o *Orkestrator) AddExpressionToStorage(expr string, vars map[string]float64, user string) (string, error) {
 	id := models.MakeID()
 
+	variables, err := encodeVariables(vars)
+	if err != nil {
+		return id, err
+	}
+
 	var q = `
-	INSERT INTO expressions (user, id, expr, status, result, error) values ($1, $2, $3, $4, $5, $6)
+	INSERT INTO expressions (user, id, expr, status, result, error, variables) values ($1, $2, $3, $4, $5, $6, $7)
 	`
-	_, err := o.exprs.ExecContext(o.ctx, q, user, id, expr, models.StatusPending, 0, "")
+	_, err = o.exprs.ExecContext(o.ctx, q, user, id, expr, models.StatusPending, 0, "", variables)
 	if err != nil {
 		return id, err
 	}
 	return id, nil
 }
 
+// сохранить значения идентификаторов и ссылок, использованных при вычислении выражения
+func (o *Orkestrator) SetExpressionVariables(id string, vars map[string]float64) error {
+	variables, err := encodeVariables(vars)
+	if err != nil {
+		return err
+	}
+
+	var q = "UPDATE expressions SET variables = $1 WHERE id = $2"
+	_, err = o.exprs.ExecContext(o.ctx, q, variables, id)
+	return err
+}
+
+// сохранить режим вычисления выражения
+func (o *Orkestrator) SetExpressionMode(id string, mode string) error {
+	var q = "UPDATE expressions SET mode = $1 WHERE id = $2"
+	_, err := o.exprs.ExecContext(o.ctx, q, mode, id)
+	return err
+}
+
+// сохранить флаг no_cache выражения, чтобы продолженное после перезапуска
+// вычисление тоже не брало результаты операций из кэша
+func (o *Orkestrator) SetExpressionNoCache(id string, noCache bool) error {
+	var q = "UPDATE expressions SET no_cache = $1 WHERE id = $2"
+	_, err := o.exprs.ExecContext(o.ctx, q, noCache, id)
+	return err
+}
+
+// сохранить план вычисления выражения (см. Orkestrator.Recover)
+func (o *Orkestrator) SetExpressionPlan(id string, p *plan) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	var q = "UPDATE expressions SET plan = $1 WHERE id = $2"
+	_, err = o.exprs.ExecContext(o.ctx, q, string(data), id)
+	return err
+}
+
+// сохранить вычисленные шаги выражения
+func (o *Orkestrator) SetExpressionSteps(id string, steps []models.Step) error {
+	var data []byte
+	if len(steps) > 0 {
+		var err error
+		if data, err = json.Marshal(steps); err != nil {
+			return err
+		}
+	}
+
+	var q = "UPDATE expressions SET steps = $1 WHERE id = $2"
+	_, err := o.exprs.ExecContext(o.ctx, q, string(data), id)
+	return err
+}
+
+// сохранить точную запись результата (в режимах, кроме float)
+func (o *Orkestrator) SetExpressionValue(id string, value string) error {
+	var q = "UPDATE expressions SET value = $1 WHERE id = $2 AND status = $3"
+	_, err := o.exprs.ExecContext(o.ctx, q, value, id, models.StatusPending)
+	return err
+}
+
+// сохранить число попыток вычисления операций выражения
+func (o *Orkestrator) SetExpressionAttempts(id string, attempts int) error {
+	var q = "UPDATE expressions SET attempts = $1 WHERE id = $2"
+	_, err := o.exprs.ExecContext(o.ctx, q, attempts, id)
+	return err
+}
+
 // получение всех выражений
 func (o *Orkestrator) GetAllExpressions(user string) (map[string]models.Expression, error) {
 	expressions := make(map[string]models.Expression)
-	var q = "SELECT id, expr, status, result, error FROM expressions WHERE user = $1"
+	var q = "SELECT id, expr, status, result, error, variables, mode, value, attempts, steps FROM expressions WHERE user = $1"
 
 	rows, err := o.exprs.QueryContext(o.ctx, q, user)
 	if err != nil {
@@ -77,10 +243,22 @@ func (o *Orkestrator) GetAllExpressions(user string) (map[string]models.Expressi
 
 	for rows.Next() {
 		e := models.Expression{}
-		err := rows.Scan(&e.ID, &e.Expr, &e.Status, &e.Result, &e.Error)
+		var variables, mode, value, steps sql.NullString
+		var attempts sql.NullInt64
+		err := rows.Scan(&e.ID, &e.Expr, &e.Status, &e.Result, &e.Error, &variables, &mode, &value, &attempts, &steps)
+		if err != nil {
+			return nil, err
+		}
+		e.Variables, err = decodeVariables(variables)
+		if err != nil {
+			return nil, err
+		}
+		e.Steps, err = decodeSteps(steps)
 		if err != nil {
 			return nil, err
 		}
+		e.Mode, e.Value, e.Attempts = mode.String, value.String, int(attempts.Int64)
+		o.describeValue(&e)
 		expressions[e.ID] = e
 	}
 	return expressions, nil
@@ -89,19 +267,32 @@ func (o *Orkestrator) GetAllExpressions(user string) (map[string]models.Expressi
 // получить конкретное выражение по id
 func (o *Orkestrator) GetExpression(id string, user string) (models.Expression, error) {
 	e := models.Expression{}
-	var q = "SELECT id, expr, status, result, error FROM expressions WHERE id = $1 AND user = $2"
-	err := o.exprs.QueryRowContext(o.ctx, q, id, user).Scan(&e.ID, &e.Expr, &e.Status, &e.Result, &e.Error)
+	var variables, mode, value, steps sql.NullString
+	var attempts sql.NullInt64
+	var q = "SELECT id, expr, status, result, error, variables, mode, value, attempts, steps FROM expressions WHERE id = $1 AND user = $2"
+	err := o.exprs.QueryRowContext(o.ctx, q, id, user).Scan(&e.ID, &e.Expr, &e.Status, &e.Result, &e.Error, &variables, &mode, &value, &attempts, &steps)
 	if err != nil {
 		return models.Expression{}, models.ErrCannotFindObject
 	}
+	e.Variables, err = decodeVariables(variables)
+	if err != nil {
+		return models.Expression{}, err
+	}
+	e.Steps, err = decodeSteps(steps)
+	if err != nil {
+		return models.Expression{}, err
+	}
+	e.Mode, e.Value, e.Attempts = mode.String, value.String, int(attempts.Int64)
+	o.describeValue(&e)
 	return e, nil
 }
 
-// изменить статус и результат/ошибку выражения
+// изменить статус и результат/ошибку выражения. статус меняется только у вычисляющегося
+// выражения, поэтому отмененное выражение не становится завершенным
 func (o *Orkestrator) ChangeExpressionStatus(id string, res float64, ok bool, err string) error {
 	if ok {
-		var q = "UPDATE expressions SET status = $1, result = $2 WHERE id = $3"
-		_, err2 := o.exprs.ExecContext(o.ctx, q, models.StatusCompleted, res, id)
+		var q = "UPDATE expressions SET status = $1, result = $2 WHERE id = $3 AND status = $4"
+		_, err2 := o.exprs.ExecContext(o.ctx, q, models.StatusCompleted, res, id, models.StatusPending)
 		if err2 != nil {
 			return err2
 		}
@@ -109,8 +300,8 @@ func (o *Orkestrator) ChangeExpressionStatus(id string, res float64, ok bool, er
 		return nil
 	}
 
-	var q = "UPDATE expressions SET status = $1, error = $2 WHERE id = $3"
-	_, err2 := o.exprs.ExecContext(o.ctx, q, models.StatusFailed, err, id)
+	var q = "UPDATE expressions SET status = $1, error = $2 WHERE id = $3 AND status = $4"
+	_, err2 := o.exprs.ExecContext(o.ctx, q, models.StatusFailed, err, id, models.StatusPending)
 	if err2 != nil {
 		return err2
 	}
@@ -118,6 +309,18 @@ func (o *Orkestrator) ChangeExpressionStatus(id string, res float64, ok bool, er
 	return nil
 }
 
+// завершить вычисление выражения без результата: статус cancelled или timeout
+// и ошибка. завершенные выражения не меняются
+func (o *Orkestrator) SetExpressionStatus(id string, status string, err string) error {
+	var q = "UPDATE expressions SET status = $1, error = $2 WHERE id = $3 AND status = $4"
+	_, err2 := o.exprs.ExecContext(o.ctx, q, status, err, id, models.StatusPending)
+	if err2 != nil {
+		return err2
+	}
+	o.log.Info(id + ": expression status changed: " + status)
+	return nil
+}
+
 func (o *Orkestrator) Clear(user string) (int64, error) {
 	q := `DELETE FROM expressions WHERE user = $1`
 
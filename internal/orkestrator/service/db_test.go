@@ -13,6 +13,62 @@ func setupTestDB(t *testing.T) *sql.DB {
 	db, err := sql.Open("sqlite3", ":memory:")
 	assert.NoError(t, err)
 
+	// база в памяти живет, пока открыто соединение
+	db.SetMaxOpenConns(1)
+
+	err = createExpressionsTable(context.Background(), db)
+	assert.NoError(t, err)
+	err = createVariablesTable(context.Background(), db)
+	assert.NoError(t, err)
+	err = createStepsTable(context.Background(), db)
+	assert.NoError(t, err)
+
+	return db
+}
+
+func TestAddExpressionToStorage_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		exprs: db,
+		ctx:   context.Background(),
+	}
+
+	id, err := o.AddExpressionToStorage("2+2", nil, "testuser")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+}
+
+func TestExpressionVariables_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		exprs: db,
+		ctx:   context.Background(),
+	}
+
+	vars := map[string]float64{"a": 2, "x": 3, "b": 1}
+	id, err := o.AddExpressionToStorage("a*x+b", vars, "testuser")
+	assert.NoError(t, err)
+
+	expr, err := o.GetExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, vars, expr.Variables)
+
+	exprs, err := o.GetAllExpressions("testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, vars, exprs[id].Variables)
+}
+
+// в таблицу старой версии добавляются недостающие столбцы
+func TestCreateExpressionsTable_Migration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
 	_, err = db.Exec(`
 		CREATE TABLE expressions(
 			user TEXT NOT NULL,
@@ -22,24 +78,23 @@ func setupTestDB(t *testing.T) *sql.DB {
 			result REAL,
 			error TEXT
 		);
+		INSERT INTO expressions (user, id, expr, status, result, error)
+		VALUES ('testuser', '123', '2+2', 'completed', 4, '');
 	`)
 	assert.NoError(t, err)
 
-	return db
-}
-
-func TestAddExpressionToStorage_Integration(t *testing.T) {
-	db := setupTestDB(t)
-	defer db.Close()
+	err = createExpressionsTable(context.Background(), db)
+	assert.NoError(t, err)
 
 	o := &Orkestrator{
 		exprs: db,
 		ctx:   context.Background(),
 	}
 
-	id, err := o.AddExpressionToStorage("2+2", "testuser")
+	expr, err := o.GetExpression("123", "testuser")
 	assert.NoError(t, err)
-	assert.NotEmpty(t, id)
+	assert.Equal(t, 4.0, expr.Result)
+	assert.Nil(t, expr.Variables)
 }
 
 func TestGetExpression_Integration(t *testing.T) {
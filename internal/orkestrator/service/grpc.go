@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+)
+
+// gRPC сервер оркестратора: выдает агентам задачи из очереди и принимает результаты
+type TaskServer struct {
+	o *Orkestrator
+
+	pb.UnimplementedCalcServiceServer
+}
+
+func (s *TaskServer) GetTask(ctx context.Context, in *pb.GetTaskRequest) (*pb.Task, error) {
+	t, ok := s.o.queue.pop()
+	if !ok {
+		return nil, status.Error(codes.NotFound, "no tasks")
+	}
+	if err := s.o.registry.take(in.AgentId, t); err != nil {
+		s.o.queue.requeue(t)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	// срок операции отсчитывается с этого момента (см. Orkestrator.enqueue)
+	s.o.queue.take(t.ID)
+
+	s.o.log.Info(t.ID + ": task sent to agent")
+	task := pb.NewTask(t.ID, t.Operation, t.Arg1, t.Arg2)
+	if t.Mode != "" {
+		task.Mode = t.Mode
+		task.Arg1Exact, task.Arg2Exact = t.Arg1Exact, t.Arg2Exact
+		task.Scale, task.Rounding = int32(s.o.Config.DecimalScale), s.o.Config.DecimalRounding
+	}
+	if t.Mode == models.ModeComplex {
+		task.Arg1Imag, task.Arg2Imag = &t.Arg1Imag, &t.Arg2Imag
+	}
+	return task, nil
+}
+
+func (s *TaskServer) SubmitResult(ctx context.Context, in *pb.TaskResult) (*pb.SubmitResultResponse, error) {
+	s.o.registry.done(in.AgentId, in.Id)
+	err := s.o.queue.complete(models.Task{
+		ID:          in.Id,
+		Result:      in.Result(),
+		ResultExact: in.ResExact,
+		ResultImag:  in.ResImag,
+		Error:       in.Error,
+	})
+	if errors.Is(err, models.ErrTaskNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	s.o.log.Info(in.Id + ": got task result from agent")
+	return &pb.SubmitResultResponse{}, nil
+}
+
+func (s *TaskServer) Register(ctx context.Context, in *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	id := s.o.registry.register(models.Agent{
+		Address:        in.Address,
+		ComputingPower: int(in.ComputingPower),
+		Operations:     in.Operations,
+		Version:        in.Version,
+	})
+	s.o.log.Info(fmt.Sprintf("agent %s registered: address %q, computing power %d, version %s",
+		id, in.Address, in.ComputingPower, in.Version))
+
+	// агент с gRPC сервером получает операции от оркестратора
+	if in.Address != "" && s.o.agents != nil {
+		if err := s.o.connectAgent(in.Address); err != nil {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+	}
+
+	return &pb.RegisterResponse{
+		AgentId:             id,
+		HeartbeatIntervalMs: int32(s.o.Config.HeartbeatInterval),
+	}, nil
+}
+
+func (s *TaskServer) Heartbeat(ctx context.Context, in *pb.HeartbeatRequest) (*pb.HeartbeatResponse, error) {
+	load := models.AgentLoad{
+		Workers:     int(in.Workers),
+		BusyWorkers: int(in.BusyWorkers),
+		QueueDepth:  int(in.QueueDepth),
+		QueueSize:   int(in.QueueSize),
+	}
+	if err := s.o.registry.heartbeat(in.AgentId, load); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.HeartbeatResponse{}, nil
+}
+
+// запуск gRPC сервера оркестратора для агентов
+func (o *Orkestrator) RunGRPCServer() error {
+	o.log.Info("Server (orkestrator) for agents is starting on port " + o.Config.OrkestratorGRPCPort)
+
+	lis, err := net.Listen("tcp", ":"+o.Config.OrkestratorGRPCPort)
+	if err != nil {
+		return models.ErrStartingListener
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterCalcServiceServer(grpcServer, &TaskServer{o: o})
+
+	if err := grpcServer.Serve(lis); err != nil {
+		return models.ErrServingGRPC
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+)
+
+// операция дешевле порога Config.LocalCostThreshold: ее время из OperationTimes меньше порога.
+// такую операцию быстрее вычислить на месте, чем отправлять агенту
+func (o *Orkestrator) cheap(op string) bool {
+	return o.Config.LocalCostThreshold > 0 && o.Config.OperationTimes[op] < o.Config.LocalCostThreshold
+}
+
+// есть агенты, которые могут вычислить операцию: доступные агенты пула в режиме push,
+// зарегистрированные агенты в режиме pull
+func (o *Orkestrator) agentsAvailable() bool {
+	if o.Config.DispatchMode == models.DispatchPull {
+		return o.registry.len() > 0
+	}
+	return o.agents != nil && o.agents.healthy() > 0
+}
+
+// вычисление операции оркестратором так же, как ее вычисляет агент (см. numeric.Calculate),
+// но без задержки из OperationTimes
+func (o *Orkestrator) calculateLocal(mode string, op string, x, y numeric.Value) (numeric.Value, error) {
+	rounding := o.Config.DecimalRounding
+	if rounding == "" {
+		rounding = models.RoundHalfEven
+	}
+	return numeric.Calculate(mode, op, x, y, numeric.Options{Scale: o.Config.DecimalScale, Rounding: rounding})
+}
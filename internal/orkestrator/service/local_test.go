@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// дешевые операции и операции без доступных агентов вычисляются оркестратором.
+// в шагах выражения видно, какие операции вычислены на месте, а какие агентом
+func TestOrkestrator_Local(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	times := map[string]int{"+": 1000, "*": 10, "/": 1000}
+
+	tests := []struct {
+		name        string
+		cfg         config.Config
+		client      *flakyClient
+		req         models.CalcRequest
+		expectedRes float64
+		expectedVal string
+		expectedErr error
+		calls       int32
+		sources     []string
+	}{
+		{
+			name:        "Below threshold",
+			cfg:         config.Config{LocalCostThreshold: 100},
+			client:      &flakyClient{},
+			req:         models.CalcRequest{Expression: "(2*3)+4"},
+			expectedRes: 10,
+			calls:       1,
+			sources:     []string{models.SourceLocal, models.SourceAgent},
+		},
+		{
+			name:        "Threshold disabled",
+			client:      &flakyClient{},
+			req:         models.CalcRequest{Expression: "(2+3)+4"},
+			expectedRes: 9,
+			calls:       2,
+			sources:     []string{models.SourceAgent, models.SourceAgent},
+		},
+		{
+			name:        "No agents in pool",
+			cfg:         config.Config{LocalFallback: true},
+			req:         models.CalcRequest{Expression: "(1+2)*3"},
+			expectedRes: 9,
+			sources:     []string{models.SourceLocal, models.SourceLocal},
+		},
+		{
+			name:        "Agent unavailable",
+			cfg:         config.Config{LocalFallback: true},
+			client:      &flakyClient{err: unavailable, failures: 10},
+			req:         models.CalcRequest{Expression: "1+3"},
+			expectedRes: 4,
+			calls:       1,
+			sources:     []string{models.SourceLocal},
+		},
+		{
+			name:        "No fallback",
+			client:      &flakyClient{err: unavailable, failures: 10},
+			req:         models.CalcRequest{Expression: "1+3"},
+			expectedErr: models.ErrNoAgents,
+			calls:       1,
+		},
+		{
+			name:        "Same semantics as agent",
+			cfg:         config.Config{LocalCostThreshold: 10000, DecimalScale: 5},
+			req:         models.CalcRequest{Expression: "1/3", Mode: models.ModeDecimal},
+			expectedRes: 0.33333,
+			expectedVal: "0.33333",
+			sources:     []string{models.SourceLocal},
+		},
+		{
+			name:        "Division by zero",
+			cfg:         config.Config{LocalCostThreshold: 10000},
+			req:         models.CalcRequest{Expression: "1/0"},
+			expectedErr: models.ErrDivisionByZero,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			agents := newAgentPool(100, time.Minute, zap.NewNop())
+			if tt.client != nil {
+				agents.add("agent", tt.client)
+			}
+			cfg := tt.cfg
+			cfg.OperationTimes, cfg.RetryMaxAttempts = times, 1
+			o := &Orkestrator{
+				Config: &cfg,
+				log:    zap.NewNop(),
+				exprs:  db,
+				ctx:    context.Background(),
+				agents: agents,
+			}
+
+			e, err := o.ExpressionOperations(tt.req, "testuser")
+			if tt.client != nil {
+				assert.Equal(t, tt.calls, tt.client.calls.Load())
+			}
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRes, e.Result)
+			assert.Equal(t, tt.expectedVal, e.Value)
+
+			var sources []string
+			for _, s := range e.Steps {
+				sources = append(sources, s.Source)
+			}
+			assert.Equal(t, tt.sources, sources)
+		})
+	}
+}
+
+// в режиме pull без зарегистрированных агентов операции вычисляются оркестратором
+func TestOrkestrator_Local_Pull(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{DispatchMode: models.DispatchPull, LocalFallback: true},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+
+	e, err := o.ExpressionOperations(models.CalcRequest{Expression: "2*(3+4)"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 14.0, e.Result)
+	assert.Equal(t, 0, o.queue.len())
+
+	// зарегистрированный агент получает операции через очередь
+	o.registry.register(models.Agent{ComputingPower: 1})
+	id, err := o.SubmitExpression(models.CalcRequest{Expression: "2*(3+4)"}, "testuser")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return o.queue.len() == 1 }, time.Second, time.Millisecond)
+
+	_, err = o.CancelExpression(id, "testuser")
+	assert.NoError(t, err)
+}
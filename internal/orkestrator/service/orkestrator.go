@@ -3,9 +3,9 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strconv"
-	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -14,7 +14,9 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/expr"
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
 	pb "github.com/ArtemiySps/calc_go_final/proto"
 )
 
@@ -26,9 +28,12 @@ type Orkestrator struct {
 	exprs *sql.DB
 	ctx   context.Context
 
-	conn       *grpc.ClientConn
-	conn_err   error
-	grpcClient pb.CalcServiceClient
+	agents   *agentPool
+	registry registry
+
+	sched scheduler
+	queue taskQueue
+	cache *resultCache
 }
 
 func NewOrkestrator(cfg *config.Config, logger *zap.Logger) (*Orkestrator, error) {
@@ -42,74 +47,516 @@ func NewOrkestrator(cfg *config.Config, logger *zap.Logger) (*Orkestrator, error
 		log:    logger,
 		exprs:  db,
 		ctx:    context.TODO(),
+		cache:  newResultCache(cfg.CacheSize, time.Duration(cfg.CacheTTL)*time.Millisecond),
 	}, nil
 }
 
+// подключение к агентам из AgentAddrs (gRPC серверам). соединения устанавливаются
+// при первом вызове, поэтому недоступный агент не мешает запуску оркестратора
 func (o *Orkestrator) ConnectToServer() error {
-	o.log.Info("Client (orkestrator) is connecting to server (agent) with server address: " + o.Config.AgentHost + ":" + o.Config.AgentPort)
+	o.agents = newAgentPool(o.Config.AgentMaxFailures, time.Duration(o.Config.AgentEjectTime)*time.Millisecond, o.log)
 
-	addr := fmt.Sprintf("%s:%s", o.Config.AgentHost, o.Config.AgentPort)
+	for _, addr := range o.Config.AgentAddrs {
+		if err := o.connectAgent(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	o.conn, o.conn_err = grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// подключение к агенту и добавление его в пул
+func (o *Orkestrator) connectAgent(addr string) error {
+	if o.agents.has(addr) {
+		return nil
+	}
+	o.log.Info("Client (orkestrator) is connecting to server (agent) with server address: " + addr)
 
-	if o.conn_err != nil {
-		o.conn_err = nil
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
 		return models.ErrConnectingGRPC
 	}
-
-	o.grpcClient = pb.NewCalcServiceClient(o.conn)
+	o.agents.add(addr, pb.NewCalcServiceClient(conn))
 	return nil
 }
 
-func (o *Orkestrator) ExpressionOperations(expr string, user string) (float64, error) {
-	id, err := o.AddExpressionToStorage(expr, user)
+// синхронное вычисление: возвращает выражение после вычисления всего выражения
+func (o *Orkestrator) ExpressionOperations(req models.CalcRequest, user string) (models.Expression, error) {
+	j, err := o.prepare(req, user)
+	if err != nil {
+		return models.Expression{}, err
+	}
+	var res numeric.Value
+	o.sched.do(j, func(j *job) {
+		res, err = o.run(j)
+	})
+	if err != nil {
+		return models.Expression{}, err
+	}
+
+	e := models.Expression{
+		ID:        j.id,
+		Expr:      req.Expression,
+		Variables: j.bindings,
+		Mode:      j.mode,
+		Status:    models.StatusCompleted,
+		Result:    res.Float,
+		Value:     res.Exact,
+		Attempts:  int(j.attempts.Load()),
+		Steps:     j.steps,
+	}
+	o.describeValue(&e)
+	return e, nil
+}
+
+// асинхронное вычисление: выражение разбирается и сохраняется, вычисление продолжается
+// в планировщике, а ID возвращается сразу. синтаксические ошибки возвращаются сразу
+func (o *Orkestrator) SubmitExpression(req models.CalcRequest, user string) (string, error) {
+	j, err := o.prepare(req, user)
+	if err != nil {
+		return "", err
+	}
+
+	o.sched.start(j, func(j *job) {
+		o.run(j)
+	})
+	return j.id, nil
+}
+
+// сохранение выражения в БД, разбор и связывание переменных и ссылок.
+// при ошибке выражение получает статус failed
+func (o *Orkestrator) prepare(req models.CalcRequest, user string) (*job, error) {
+	id, err := o.AddExpressionToStorage(req.Expression, req.Variables, user)
 	if err != nil {
 		o.log.Info(id + ": failed to add to storage")
-		return 0, err
+		return nil, err
 	}
 	o.log.Info(id + ": added to storage")
 
-	expr_rpn, err := models.InfixToPostfix(expr)
+	if err := checkVariableNames(req.Variables); err != nil {
+		o.ChangeExpressionStatus(id, 0, false, err.Error())
+		return nil, err
+	}
+	mode := o.modeOrDefault(req.Mode)
+	if !numeric.ValidMode(mode) {
+		o.ChangeExpressionStatus(id, 0, false, models.ErrUnknownMode.Error())
+		return nil, models.ErrUnknownMode
+	}
+	if err := o.SetExpressionMode(id, mode); err != nil {
+		o.ChangeExpressionStatus(id, 0, false, err.Error())
+		return nil, err
+	}
+	if err := o.SetExpressionNoCache(id, req.NoCache); err != nil {
+		o.ChangeExpressionStatus(id, 0, false, err.Error())
+		return nil, err
+	}
+
+	p, bindings, err := o.compile(req.Expression, mode, req.Variables, user)
 	if err != nil {
-		return 0, err
+		o.ChangeExpressionStatus(id, 0, false, err.Error())
+		return nil, err
+	}
+	if err := o.SetExpressionVariables(id, bindings); err != nil {
+		o.ChangeExpressionStatus(id, 0, false, err.Error())
+		return nil, err
+	}
+	// без сохраненного плана выражение после перезапуска разбирается заново
+	if err := o.SetExpressionPlan(id, p); err != nil {
+		o.log.Warn(id + ": plan is not saved: " + err.Error())
 	}
 
-	tokens := strings.Split(expr_rpn, " ")
-	var stack []float64
+	j := newJob(id, user, time.Duration(o.Config.ExpressionTimeout)*time.Millisecond)
+	j.plan, j.bindings, j.mode, j.noCache = p, bindings, mode, req.NoCache
+	return j, nil
+}
 
-	for _, token := range tokens {
-		if num, err := strconv.ParseFloat(token, 64); err == nil {
-			stack = append(stack, num)
-		} else {
-			if len(stack) < 2 {
-				o.ChangeExpressionStatus(id, 0, false, models.ErrBadExpression.Error())
-				return 0, models.ErrBadExpression
-			}
+// режим из запроса, иначе режим по умолчанию из конфигурации
+func (o *Orkestrator) modeOrDefault(mode string) string {
+	if mode == "" {
+		mode = o.Config.NumericMode
+	}
+	if mode == "" {
+		mode = models.ModeFloat
+	}
+	return mode
+}
 
-			operand2 := stack[len(stack)-1]
-			operand1 := stack[len(stack)-2]
-			stack = stack[:len(stack)-2]
+// разбор выражения, связывание переменных и ссылок и построение плана вычисления.
+// переменные vars перекрывают сохраненные переменные пользователя
+func (o *Orkestrator) compile(expression string, mode string, vars map[string]float64, user string) (*plan, map[string]float64, error) {
+	tree, err := expr.Parse(expression)
+	if err == nil && mode != models.ModeComplex {
+		err = expr.RequireReal(expression, tree)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
 
-			resp, err := o.grpcClient.Calculation(context.Background(), &pb.TaskRequest{
-				Arg1: float32(operand1),
-				Arg2: float32(operand2),
-				Opr:  token,
-			})
+	all, err := o.GetVariables(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, value := range vars {
+		all[name] = value
+	}
 
-			if err != nil {
-				o.ChangeExpressionStatus(id, 0, false, err.Error())
-				return 0, err
+	refs := make(map[string]numeric.Value)
+	bindings, err := expr.Bind(expression, tree, all, func(ref string) (float64, error) {
+		v, err := o.referencedValue(ref, user, mode)
+		if err != nil {
+			return 0, err
+		}
+		refs["@"+ref] = v
+		return v.Float, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := buildPlan(tree, bindings, refs, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, bindings, nil
+}
+
+// вычисление подготовленного выражения и запись результата/ошибки в БД.
+// если вычисление отменено, выражение получает статус cancelled, если истек срок
+// вычисления выражения или операции - статус timeout. сохраненные результаты
+// шагов (см. SaveStepResult) после вычисления удаляются
+func (o *Orkestrator) run(j *job) (numeric.Value, error) {
+	defer func() {
+		if err := o.DeleteStepResults(j.id); err != nil {
+			o.log.Error(j.id + ": " + err.Error())
+		}
+	}()
+
+	res, err := o.evaluate(j)
+	if err := o.SetExpressionAttempts(j.id, int(j.attempts.Load())); err != nil {
+		o.log.Error(j.id + ": " + err.Error())
+	}
+	if err := o.SetExpressionSteps(j.id, j.steps); err != nil {
+		o.log.Error(j.id + ": " + err.Error())
+	}
+	if err != nil {
+		switch {
+		case errors.Is(j.ctx.Err(), context.DeadlineExceeded):
+			err = models.ErrExpressionTimeout
+			o.SetExpressionStatus(j.id, models.StatusTimeout, err.Error())
+		case j.ctx.Err() != nil:
+			err = models.ErrCancelled
+			o.SetExpressionStatus(j.id, models.StatusCancelled, err.Error())
+		case errors.Is(err, models.ErrOperationTimeout):
+			o.SetExpressionStatus(j.id, models.StatusTimeout, err.Error())
+		default:
+			o.ChangeExpressionStatus(j.id, 0, false, err.Error())
+		}
+		return numeric.Value{}, err
+	}
+
+	// точная запись сохраняется до смены статуса, чтобы завершенное выражение было видно целиком
+	if res.Exact != "" {
+		if err := o.SetExpressionValue(j.id, res.Exact); err != nil {
+			o.ChangeExpressionStatus(j.id, 0, false, err.Error())
+			return numeric.Value{}, err
+		}
+	}
+	o.ChangeExpressionStatus(j.id, res.Float, true, "")
+	return res, nil
+}
+
+// отмена вычисления выражения пользователя: невыполненные операции не отправляются,
+// отправленные агентам отменяются, а задачи в очереди удаляются. возвращает выражение
+// после отмены. завершенное выражение отменить нельзя (ErrNotRunning)
+func (o *Orkestrator) CancelExpression(id string, user string) (models.Expression, error) {
+	e, err := o.GetExpression(id, user)
+	if err != nil {
+		return models.Expression{}, err
+	}
+	if e.Status != models.StatusPending {
+		return e, models.ErrNotRunning
+	}
+
+	if j, ok := o.sched.get(id); ok {
+		j.cancel()
+		<-j.done
+	} else {
+		// выражение еще не запущено: оно не будет вычислено, так как статус уже не pending
+		if err := o.SetExpressionStatus(id, models.StatusCancelled, models.ErrCancelled.Error()); err != nil {
+			return models.Expression{}, err
+		}
+	}
+	o.log.Info(id + ": expression cancelled")
+	return o.GetExpression(id, user)
+}
+
+// дробь и десятичное приближение результата в режиме rational,
+// действительная и мнимая части в режиме complex
+func (o *Orkestrator) describeValue(e *models.Expression) {
+	if e.Value == "" {
+		return
+	}
+	v := numeric.Value{Float: e.Result, Exact: e.Value}
+	if e.Mode == models.ModeComplex {
+		e.Complex = numeric.ComplexParts(e.Mode, v)
+		return
+	}
+	if e.Mode != models.ModeRational {
+		return
+	}
+	e.Fraction = numeric.Fraction(e.Mode, v)
+	e.Decimal = numeric.Approximate(e.Mode, v, numeric.Options{
+		Scale:    o.Config.DecimalScale,
+		Rounding: o.Config.DecimalRounding,
+	})
+}
+
+// результат выполнения шага плана
+type stepResult struct {
+	step int
+	res  numeric.Value
+	err  error
+	// откуда взят результат (см. models.Source*)
+	source string
+}
+
+// вычисление выражения по графу зависимостей: все шаги, операнды которых
+// известны, отправляются агенту одновременно. шаги, вычисленные до перезапуска
+// оркестратора (j.results), не отправляются, а результаты из кэша не вычисляются заново
+// (см. calculateStep). результат каждого шага сохраняется
+// в БД до отправки зависимых шагов. после первой ошибки или отмены j.ctx
+// новые шаги не отправляются, а уже отправленные отменяются и дожидаются
+func (o *Orkestrator) evaluate(j *job) (numeric.Value, error) {
+	p := j.plan
+	ctx, cancel := context.WithCancel(j.ctx)
+	defer cancel()
+
+	results := make([]numeric.Value, len(p.steps))
+	known := make([]bool, len(p.steps))
+	sources := make([]string, len(p.steps))
+	for i, r := range j.results {
+		results[i], known[i], sources[i] = r.res, true, r.source
+	}
+	defer func() {
+		j.steps = p.describe(results, sources)
+	}()
+	waiting := make([]int, len(p.steps))
+	done := make(chan stepResult, len(p.steps))
+	running := 0
+
+	dispatch := func(i int) {
+		s := p.steps[i]
+		x, y := p.resolve(s.args[0], results), p.resolve(s.args[1], results)
+		running++
+		go func() {
+			res, source, err := o.calculateStep(ctx, j, i, s.op, x, y)
+			done <- stepResult{step: i, res: res, err: err, source: source}
+		}()
+	}
+
+	for i, s := range p.steps {
+		if known[i] {
+			continue
+		}
+		waiting[i] = s.waiting(known)
+		if waiting[i] == 0 {
+			dispatch(i)
+		}
+	}
+
+	var firstErr error
+	for running > 0 {
+		r := <-done
+		running--
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+				cancel()
 			}
+			continue
+		}
+		results[r.step], known[r.step], sources[r.step] = r.res, true, r.source
+		if err := o.SaveStepResult(j.id, r.step, r.res, r.source); err != nil {
+			o.log.Error(j.id + ": step result is not saved: " + err.Error())
+		}
+		if firstErr == nil && ctx.Err() != nil {
+			firstErr = ctx.Err()
+		}
+		if firstErr != nil {
+			continue
+		}
 
-			stack = append(stack, float64(resp.Res))
+		for _, d := range p.steps[r.step].dependents {
+			if known[d] {
+				continue
+			}
+			waiting[d]--
+			if waiting[d] == 0 {
+				dispatch(d)
+			}
 		}
 	}
+	if firstErr != nil {
+		return numeric.Value{}, firstErr
+	}
+
+	return p.resolve(p.root, results), nil
+}
+
+// вычисление шага выражения. дешевые операции (см. Config.LocalCostThreshold) оркестратор
+// вычисляет сам. остальные берутся из кэша, если они там есть (и выражение не запрошено
+// с no_cache), иначе вычисляются агентом, а результат сохраняется в кэш. если доступных
+// агентов нет и задан Config.LocalFallback, операция тоже вычисляется оркестратором.
+// возвращает результат и его источник (см. models.Source*)
+func (o *Orkestrator) calculateStep(ctx context.Context, j *job, step int, op string, x, y numeric.Value) (numeric.Value, string, error) {
+	if o.cheap(op) {
+		res, err := o.calculateLocal(j.mode, op, x, y)
+		return res, models.SourceLocal, err
+	}
 
-	if len(stack) != 1 {
-		o.ChangeExpressionStatus(id, 0, false, models.ErrBadExpression.Error())
-		return 0, models.ErrBadExpression
+	key := cacheKey(j.mode, op, x, y)
+	if !j.noCache {
+		if res, ok := o.cache.get(key); ok {
+			return res, models.SourceCache, nil
+		}
+	}
+	if o.Config.LocalFallback && !o.agentsAvailable() {
+		res, err := o.calculateLocal(j.mode, op, x, y)
+		return res, models.SourceLocal, err
 	}
 
-	o.ChangeExpressionStatus(id, stack[0], true, "")
-	return stack[0], nil
+	res, err := o.calculateWithRetry(ctx, &j.attempts, stepKey(j.id, step), j.mode, op, x, y)
+	if o.Config.LocalFallback && errors.Is(err, models.ErrNoAgents) && ctx.Err() == nil {
+		o.log.Warn(fmt.Sprintf("%s: no available agents, operation %s is calculated locally", j.id, op))
+		res, err := o.calculateLocal(j.mode, op, x, y)
+		return res, models.SourceLocal, err
+	}
+	if err != nil {
+		return numeric.Value{}, "", err
+	}
+	o.cache.put(key, res)
+	return res, models.SourceAgent, nil
+}
+
+// вычисление одной операции агентом за ее время из OperationTimes плюс OperationSlack
+// (ErrOperationTimeout). срок отсчитывается с начала вычисления: время в очереди агента
+// и в очереди задач не учитывается. при отмене ctx вызов агента отменяется, а задача
+// удаляется из очереди. key - ключ операции (см. stepKey), пустой ключ - операция вне выражения
+func (o *Orkestrator) calculate(ctx context.Context, key string, mode string, op string, x, y numeric.Value) (numeric.Value, error) {
+	var timeout time.Duration
+	if o.Config.OperationSlack > 0 {
+		timeout = time.Duration(o.Config.OperationTimes[op]+o.Config.OperationSlack) * time.Millisecond
+	}
+
+	res, err := o.dispatch(ctx, key, mode, op, x, y, timeout)
+	if errors.Is(err, models.ErrOperationTimeout) {
+		return numeric.Value{}, fmt.Errorf("%w: %s after %s", models.ErrOperationTimeout, op, timeout)
+	}
+	return res, err
+}
+
+// отправка операции агенту. в режиме pull операция становится задачей
+// в очереди, и результат ждется от агента, забравшего ее. timeout - срок операции
+// с начала вычисления, 0 - без ограничения
+func (o *Orkestrator) dispatch(ctx context.Context, key string, mode string, op string, x, y numeric.Value, timeout time.Duration) (numeric.Value, error) {
+	if o.Config.DispatchMode == models.DispatchPull {
+		return o.enqueue(ctx, key, mode, op, x, y, timeout)
+	}
+
+	req := pb.NewTaskRequest(op, x.Float, y.Float)
+	if mode != models.ModeFloat {
+		req.Mode = mode
+		req.Arg1Exact, req.Arg2Exact = x.Exact, y.Exact
+		req.Scale, req.Rounding = int32(o.Config.DecimalScale), o.Config.DecimalRounding
+	}
+	if mode == models.ModeComplex {
+		req.Arg1Imag, req.Arg2Imag = &x.Imag, &y.Imag
+	}
+	// срок операции соблюдает агент: только он знает, когда воркер начал ее вычислять.
+	// на случай зависшего агента или агента без поддержки timeout_ms вызов ограничен и
+	// на стороне оркестратора с запасом AgentQueueTimeout на ожидание в очереди агента
+	req.TimeoutMs = int32(timeout / time.Millisecond)
+	callCtx := ctx
+	if timeout > 0 && o.Config.AgentQueueTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout+time.Duration(o.Config.AgentQueueTimeout)*time.Millisecond)
+		defer cancel()
+	}
+
+	resp, err := o.agents.calculate(callCtx, req)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return numeric.Value{}, models.ErrOperationTimeout
+	}
+	if err != nil {
+		return numeric.Value{}, err
+	}
+	return exactResult(mode, resp.Result(), resp.ResImag, resp.ResExact)
+}
+
+// постановка операции в очередь задач и ожидание результата. ID задачи - ключ операции,
+// поэтому результат, который агент вернет после перезапуска оркестратора или повтора,
+// принимается для той же операции, а не вычисляется заново. срок операции timeout
+// отсчитывается с момента, когда агент забрал задачу
+func (o *Orkestrator) enqueue(ctx context.Context, key string, mode string, op string, x, y numeric.Value, timeout time.Duration) (numeric.Value, error) {
+	if key == "" {
+		key = models.MakeID()
+	}
+	t := &models.Task{
+		ID:        key,
+		Arg1:      x.Float,
+		Arg2:      y.Float,
+		Operation: op,
+	}
+	if mode != models.ModeFloat {
+		t.Mode = mode
+		t.Arg1Exact, t.Arg2Exact = x.Exact, y.Exact
+		t.Arg1Imag, t.Arg2Imag = x.Imag, y.Imag
+	}
+	done, taken := o.queue.push(t)
+
+	var res models.Task
+	var deadline <-chan time.Time
+wait:
+	for {
+		select {
+		case res = <-done:
+			break wait
+		case <-taken:
+			taken = nil
+			if timeout > 0 {
+				timer := time.NewTimer(timeout)
+				defer timer.Stop()
+				deadline = timer.C
+			}
+		case <-deadline:
+			o.queue.drop(t.ID)
+			return numeric.Value{}, models.ErrOperationTimeout
+		case <-ctx.Done():
+			o.queue.drop(t.ID)
+			return numeric.Value{}, ctx.Err()
+		}
+	}
+
+	if res.Error != "" {
+		return numeric.Value{}, models.ErrorFromText(res.Error)
+	}
+	return exactResult(mode, res.Result, res.ResultImag, res.ResultExact)
+}
+
+// результат операции от агента. агент, не знающий точных режимов, вычисляет
+// операцию во float и не возвращает точную запись (мнимую часть в режиме complex) -
+// такой результат не принимается
+func exactResult(mode string, res float64, imag *float64, exact string) (numeric.Value, error) {
+	switch mode {
+	case models.ModeFloat:
+		return numeric.Value{Float: res}, nil
+	case models.ModeComplex:
+		if imag == nil {
+			return numeric.Value{}, models.ErrModeNotSupported
+		}
+		return numeric.Complex(res, *imag), nil
+	}
+	if exact == "" {
+		return numeric.Value{}, models.ErrModeNotSupported
+	}
+	return numeric.Parse(mode, exact)
 }
@@ -2,16 +2,22 @@ package service
 
 import (
 	"context"
-	"database/sql"
+	"math"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/expr"
 	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
 	pb "github.com/ArtemiySps/calc_go_final/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type MockCalcClient struct {
@@ -23,6 +29,40 @@ func (m *MockCalcClient) Calculation(ctx context.Context, in *pb.TaskRequest, op
 	return args.Get(0).(*pb.ResResponse), args.Error(1)
 }
 
+func (m *MockCalcClient) GetTask(ctx context.Context, in *pb.GetTaskRequest, opts ...grpc.CallOption) (*pb.Task, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*pb.Task), args.Error(1)
+}
+
+func (m *MockCalcClient) SubmitResult(ctx context.Context, in *pb.TaskResult, opts ...grpc.CallOption) (*pb.SubmitResultResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*pb.SubmitResultResponse), args.Error(1)
+}
+
+func (m *MockCalcClient) Register(ctx context.Context, in *pb.RegisterRequest, opts ...grpc.CallOption) (*pb.RegisterResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*pb.RegisterResponse), args.Error(1)
+}
+
+func (m *MockCalcClient) Heartbeat(ctx context.Context, in *pb.HeartbeatRequest, opts ...grpc.CallOption) (*pb.HeartbeatResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*pb.HeartbeatResponse), args.Error(1)
+}
+
+// пул из одного агента с заданным клиентом
+func singleAgent(client pb.CalcServiceClient) *agentPool {
+	p := newAgentPool(1, time.Minute, zap.NewNop())
+	p.add("test", client)
+	return p
+}
+
+// ожидание окончания фонового вычисления выражения
+func waitJob(o *Orkestrator, id string) {
+	if j, ok := o.sched.get(id); ok {
+		<-j.done
+	}
+}
+
 func TestOrkestrator_ExpressionOperations(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -40,6 +80,23 @@ func TestOrkestrator_ExpressionOperations(t *testing.T) {
 			expectedRes: 5,
 			expectedErr: nil,
 		},
+		{
+			// старый агент: результат только во float-поле
+			name:        "Decimal addition",
+			expr:        "2.5+1e-1",
+			mockRes:     2.6,
+			mockErr:     nil,
+			expectedRes: float64(float32(2.6)),
+			expectedErr: nil,
+		},
+		{
+			name:        "Unary minus",
+			expr:        "-3+5",
+			mockRes:     2,
+			mockErr:     nil,
+			expectedRes: 2,
+			expectedErr: nil,
+		},
 		{
 			name:        "Division by zero",
 			expr:        "2/0",
@@ -57,35 +114,22 @@ func TestOrkestrator_ExpressionOperations(t *testing.T) {
 				&pb.ResResponse{Res: tt.mockRes}, tt.mockErr,
 			)
 
-			db, err := sql.Open("sqlite3", ":memory:")
-			assert.NoError(t, err)
+			db := setupTestDB(t)
 			defer db.Close()
 
-			_, err = db.Exec(`
-				CREATE TABLE expressions(
-					user TEXT NOT NULL,
-					id TEXT NOT NULL,
-					expr TEXT NOT NULL,
-					status TEXT NOT NULL,
-					result REAL,
-					error TEXT
-				);
-			`)
-			assert.NoError(t, err)
-
 			cfg := &config.Config{OperationTimes: map[string]int{"+": 0, "/": 0}}
 			logger := zap.NewNop()
 			o := &Orkestrator{
-				Config:     cfg,
-				log:        logger,
-				exprs:      db,
-				ctx:        context.Background(),
-				grpcClient: mockClient,
+				Config: cfg,
+				log:    logger,
+				exprs:  db,
+				ctx:    context.Background(),
+				agents: singleAgent(mockClient),
 			}
 
-			res, err := o.ExpressionOperations(tt.expr, "testuser")
+			res, err := o.ExpressionOperations(models.CalcRequest{Expression: tt.expr}, "testuser")
 
-			assert.Equal(t, tt.expectedRes, res)
+			assert.Equal(t, tt.expectedRes, res.Result)
 			if tt.expectedErr != nil {
 				assert.ErrorIs(t, err, tt.expectedErr)
 			} else {
@@ -95,3 +139,546 @@ func TestOrkestrator_ExpressionOperations(t *testing.T) {
 		})
 	}
 }
+
+// функции с переменным числом аргументов вычисляются попарно
+func TestOrkestrator_ExpressionOperations_Functions(t *testing.T) {
+	isOp := func(op string, arg1, arg2 float32) any {
+		return mock.MatchedBy(func(in *pb.TaskRequest) bool {
+			return in.Opr == op && in.Arg1 == arg1 && in.Arg2 == arg2
+		})
+	}
+
+	mockClient := new(MockCalcClient)
+	mockClient.On("Calculation", mock.Anything, isOp("sqrt", 16, 0)).Return(&pb.ResResponse{Res: 4}, nil).Once()
+	mockClient.On("Calculation", mock.Anything, isOp("max", 3, 5)).Return(&pb.ResResponse{Res: 5}, nil).Once()
+	mockClient.On("Calculation", mock.Anything, isOp("max", 5, 4)).Return(&pb.ResResponse{Res: 5}, nil).Once()
+	mockClient.On("Calculation", mock.Anything, isOp("*", 4, 5)).Return(&pb.ResResponse{Res: 20}, nil).Once()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: singleAgent(mockClient),
+	}
+
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "sqrt(16)*max(3,5,4)"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, res.Result)
+	mockClient.AssertExpectations(t)
+}
+
+// переменные и константы подставляются, неизвестные идентификаторы дают ошибку с позицией
+func TestOrkestrator_ExpressionOperations_Variables(t *testing.T) {
+	mockClient := new(MockCalcClient)
+	mockClient.On("Calculation", mock.Anything, mock.MatchedBy(func(in *pb.TaskRequest) bool {
+		return in.Opr == "*" && in.Arg1 == 2 && in.Arg2 == float32(math.Pi)
+	})).Return(&pb.ResResponse{Res: float32(2 * math.Pi)}, nil).Once()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: singleAgent(mockClient),
+	}
+
+	res, err := o.ExpressionOperations(models.CalcRequest{
+		Expression: "r*pi",
+		Variables:  map[string]float64{"r": 2},
+	}, "testuser")
+	assert.NoError(t, err)
+	assert.InDelta(t, 2*math.Pi, res.Result, 1e-6)
+
+	_, err = o.ExpressionOperations(models.CalcRequest{
+		Expression: "a*x+b",
+		Variables:  map[string]float64{"a": 2, "x": 3},
+	}, "testuser")
+	assert.ErrorIs(t, err, models.ErrUndefinedVariable)
+	var perr *expr.ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 4, perr.Offset)
+		assert.Equal(t, `"b"`, perr.Token)
+	}
+
+	mockClient.AssertExpectations(t)
+}
+
+// асинхронное вычисление: выражение сначала pending, после вычисления - completed
+func TestOrkestrator_SubmitExpression(t *testing.T) {
+	release := make(chan time.Time)
+	mockClient := new(MockCalcClient)
+	mockClient.On("Calculation", mock.Anything, mock.Anything).
+		Return(&pb.ResResponse{Res: 4}, nil).WaitUntil(release).Once()
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: singleAgent(mockClient),
+	}
+
+	id, err := o.SubmitExpression(models.CalcRequest{Expression: "2+2"}, "testuser")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	e, err := o.GetExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusPending, e.Status)
+	_, running := o.sched.get(id)
+	assert.True(t, running)
+
+	close(release)
+	waitJob(o, id)
+
+	e, err = o.GetExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCompleted, e.Status)
+	assert.Equal(t, 4.0, e.Result)
+	_, running = o.sched.get(id)
+	assert.False(t, running)
+
+	// синтаксические ошибки возвращаются сразу
+	_, err = o.SubmitExpression(models.CalcRequest{Expression: "2+"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrBadExpression)
+
+	mockClient.AssertExpectations(t)
+}
+
+// отмена в режиме pull: задача удаляется из очереди, выражение получает статус cancelled
+func TestOrkestrator_CancelExpression_Pull(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{DispatchMode: models.DispatchPull},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+	server := &TaskServer{o: o}
+
+	id, err := o.SubmitExpression(models.CalcRequest{Expression: "(1+2)*3"}, "testuser")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return o.queue.len() == 1 }, time.Second, time.Millisecond)
+
+	// чужое выражение отменить нельзя
+	_, err = o.CancelExpression(id, "otheruser")
+	assert.ErrorIs(t, err, models.ErrCannotFindObject)
+
+	e, err := o.CancelExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, e.Status)
+	assert.Equal(t, models.ErrCancelled.Error(), e.Error)
+	assert.Equal(t, 0, o.queue.len())
+	_, running := o.sched.get(id)
+	assert.False(t, running)
+
+	_, err = server.GetTask(context.Background(), &pb.GetTaskRequest{})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// повторная отмена и ссылка на отмененное выражение
+	_, err = o.CancelExpression(id, "testuser")
+	assert.ErrorIs(t, err, models.ErrNotRunning)
+	_, err = o.SubmitExpression(models.CalcRequest{Expression: "@" + id + "+1"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrReferenceCancelled)
+}
+
+// отмена в режиме push: вызов агента отменяется через ctx, и агент прерывает операцию,
+// не дожидаясь ее окончания
+func TestOrkestrator_CancelExpression_Push(t *testing.T) {
+	ta := startTestAgentWithTimes(t, map[string]int{"+": 10000})
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{AgentAddrs: []string{ta.addr}},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := o.ExpressionOperations(models.CalcRequest{Expression: "1+2"}, "testuser")
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return ta.calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	exprs, err := o.GetAllExpressions("testuser")
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 1)
+
+	start := time.Now()
+	for id := range exprs {
+		e, err := o.CancelExpression(id, "testuser")
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusCancelled, e.Status)
+	}
+	assert.ErrorIs(t, <-errs, models.ErrCancelled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// операция, не уложившаяся в свое время плюс запас, прерывается,
+// выражение получает статус timeout
+func TestOrkestrator_OperationTimeout(t *testing.T) {
+	ta := startTestAgentWithTimes(t, map[string]int{"+": 10000, "*": 0})
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			AgentAddrs:     []string{ta.addr},
+			OperationTimes: map[string]int{"+": 10, "*": 10},
+			OperationSlack: 50,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	start := time.Now()
+	_, err := o.ExpressionOperations(models.CalcRequest{Expression: "2*3+1"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrOperationTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+
+	exprs, err := o.GetAllExpressions("testuser")
+	assert.NoError(t, err)
+	for _, e := range exprs {
+		assert.Equal(t, models.StatusTimeout, e.Status)
+		assert.Contains(t, e.Error, models.ErrOperationTimeout.Error())
+		assert.Zero(t, e.Result)
+	}
+
+	// операции, уложившиеся в срок, вычисляются как обычно
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "2*3"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 6.0, res.Result)
+}
+
+// агент, который не соблюдает timeout_ms и не отвечает, пока вызов не отменят
+type hangingCalcClient struct {
+	pb.CalcServiceClient
+}
+
+func (c *hangingCalcClient) Calculation(ctx context.Context, in *pb.TaskRequest, opts ...grpc.CallOption) (*pb.ResResponse, error) {
+	<-ctx.Done()
+	return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+// в режиме push зависший агент не держит операцию дольше ее срока плюс AgentQueueTimeout,
+// даже если срок всего выражения не ограничен
+func TestOrkestrator_OperationTimeout_HangingAgent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			OperationTimes:    map[string]int{"+": 10},
+			OperationSlack:    50,
+			AgentQueueTimeout: 100,
+		},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: singleAgent(&hangingCalcClient{}),
+	}
+
+	start := time.Now()
+	_, err := o.ExpressionOperations(models.CalcRequest{Expression: "2+3"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrOperationTimeout)
+	assert.Less(t, time.Since(start), time.Second)
+
+	exprs, err := o.GetAllExpressions("testuser")
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 1)
+	for _, e := range exprs {
+		assert.Equal(t, models.StatusTimeout, e.Status)
+	}
+}
+
+// время в очереди агента не входит в срок операции: параллельных операций больше,
+// чем воркеров, и последняя ждет в очереди дольше своего срока
+func TestOrkestrator_OperationTimeout_Queued(t *testing.T) {
+	times := map[string]int{"+": 100, "*": 100}
+	ta := startTestAgentWithTimes(t, times)
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			AgentAddrs:     []string{ta.addr},
+			OperationTimes: times,
+			OperationSlack: 50,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "(1+2)*(3+4)*(5+6)"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 231.0, res.Result)
+}
+
+// в режиме pull срок операции отсчитывается с момента, когда агент забрал задачу
+func TestOrkestrator_OperationTimeout_Pull(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			DispatchMode:   models.DispatchPull,
+			OperationTimes: map[string]int{"+": 0, "*": 0},
+			OperationSlack: 50,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	server := &TaskServer{o: o}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := o.ExpressionOperations(models.CalcRequest{Expression: "(1+2)*(3+4)"}, "testuser")
+		errs <- err
+	}()
+
+	// задачи ждут в очереди дольше срока операции
+	assert.Eventually(t, func() bool { return o.queue.len() == 2 }, time.Second, time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	for range 2 {
+		task := takeTask(t, server)
+		x, y := task.Args()
+		result := &pb.TaskResult{Id: task.Id}
+		result.SetResult(x + y)
+		_, err := server.SubmitResult(context.Background(), result)
+		assert.NoError(t, err)
+	}
+
+	// забранная задача без результата прерывается через срок операции
+	task := takeTask(t, server)
+	assert.Equal(t, "*", task.Opr)
+	assert.ErrorIs(t, <-errs, models.ErrOperationTimeout)
+}
+
+// выражение, не уложившееся в ExpressionTimeout, получает статус timeout,
+// а его задачи удаляются из очереди
+func TestOrkestrator_ExpressionTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			DispatchMode:      models.DispatchPull,
+			ExpressionTimeout: 50,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+
+	// агентов нет, задача остается в очереди до истечения срока
+	_, err := o.ExpressionOperations(models.CalcRequest{Expression: "(1+2)*3"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrExpressionTimeout)
+	assert.Equal(t, 0, o.queue.len())
+
+	exprs, err := o.GetAllExpressions("testuser")
+	assert.NoError(t, err)
+	for id, e := range exprs {
+		assert.Equal(t, models.StatusTimeout, e.Status)
+		assert.Equal(t, models.ErrExpressionTimeout.Error(), e.Error)
+
+		_, err = o.CancelExpression(id, "testuser")
+		assert.ErrorIs(t, err, models.ErrNotRunning)
+		_, err = o.SubmitExpression(models.CalcRequest{Expression: "@" + id}, "testuser")
+		assert.ErrorIs(t, err, models.ErrReferenceTimeout)
+	}
+}
+
+// клиент, который вычисляет операции с задержкой и считает одновременные вызовы
+type delayCalcClient struct {
+	pb.CalcServiceClient
+	delay time.Duration
+
+	mu      sync.Mutex
+	calls   int
+	current int
+	peak    int
+}
+
+func (c *delayCalcClient) Calculation(ctx context.Context, in *pb.TaskRequest, opts ...grpc.CallOption) (*pb.ResResponse, error) {
+	c.mu.Lock()
+	c.calls++
+	c.current++
+	c.peak = max(c.peak, c.current)
+	c.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	c.mu.Lock()
+	c.current--
+	c.mu.Unlock()
+
+	var res float32
+	switch in.Opr {
+	case "+":
+		res = in.Arg1 + in.Arg2
+	case "-":
+		res = in.Arg1 - in.Arg2
+	case "*":
+		res = in.Arg1 * in.Arg2
+	case "/":
+		if in.Arg2 == 0 {
+			return nil, models.ErrDivisionByZero
+		}
+		res = in.Arg1 / in.Arg2
+	}
+	return &pb.ResResponse{Res: res}, nil
+}
+
+// независимые подвыражения вычисляются одновременно: время определяется
+// глубиной дерева (3 операции), а не общим числом операций (7)
+func TestOrkestrator_ExpressionOperations_Parallel(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	client := &delayCalcClient{delay: delay}
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: singleAgent(client),
+	}
+
+	start := time.Now()
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "((1+2)*(3+4))-((5+6)*-(7+8))"}, "testuser")
+	elapsed := time.Since(start)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 186.0, res.Result)
+	assert.Equal(t, 7, client.calls)
+	assert.Equal(t, 4, client.peak)
+	assert.GreaterOrEqual(t, elapsed, 3*delay)
+	assert.Less(t, elapsed, 5*delay)
+}
+
+// после ошибки новые шаги не отправляются, выражение получает статус failed
+func TestOrkestrator_ExpressionOperations_ParallelError(t *testing.T) {
+	client := &delayCalcClient{delay: 10 * time.Millisecond}
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: singleAgent(client),
+	}
+
+	id, err := o.SubmitExpression(models.CalcRequest{Expression: "(1/0)*(2+3)+4"}, "testuser")
+	assert.NoError(t, err)
+	waitJob(o, id)
+
+	assert.Equal(t, 2, client.calls)
+	e, err := o.GetExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusFailed, e.Status)
+	assert.Equal(t, models.ErrDivisionByZero.Error(), e.Error)
+}
+
+// режим pull: операции попадают в очередь, агент забирает их через GetTask
+// и возвращает результаты через SubmitResult
+func TestOrkestrator_ExpressionOperations_Pull(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{DispatchMode: models.DispatchPull},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+	server := &TaskServer{o: o}
+
+	// в пустой очереди задач нет
+	_, err := server.GetTask(context.Background(), &pb.GetTaskRequest{})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// агент: забирает задачи, пока не будут получены n результатов
+	agent := func(n int) {
+		for n > 0 {
+			task, err := server.GetTask(context.Background(), &pb.GetTaskRequest{})
+			if err != nil {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+
+			res := &pb.TaskResult{Id: task.Id}
+			switch task.Opr {
+			case "+":
+				res.Res = task.Arg1 + task.Arg2
+			case "*":
+				res.Res = task.Arg1 * task.Arg2
+			case "/":
+				res.Error = models.ErrDivisionByZero.Error()
+			}
+			_, err = server.SubmitResult(context.Background(), res)
+			assert.NoError(t, err)
+			n--
+		}
+	}
+
+	go agent(3)
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "(1+2)*(3+4)"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 21.0, res.Result)
+
+	// ошибка агента передается текстом и восстанавливается в ту же ошибку
+	go agent(1)
+	_, err = o.ExpressionOperations(models.CalcRequest{Expression: "1/0"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrDivisionByZero)
+	assert.Equal(t, 0, o.queue.len())
+
+	// результат неизвестной задачи
+	_, err = server.SubmitResult(context.Background(), &pb.TaskResult{Id: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// агент, не знающий точных режимов, не возвращает точную запись результата
+func TestOrkestrator_Calculate_ModeNotSupported(t *testing.T) {
+	mockClient := new(MockCalcClient)
+	mockClient.On("Calculation", mock.Anything, mock.Anything).Return(pb.NewResResponse(0.30000000000000004), nil)
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		agents: singleAgent(mockClient),
+	}
+
+	x, _ := numeric.Parse(models.ModeDecimal, "0.1")
+	y, _ := numeric.Parse(models.ModeDecimal, "0.2")
+	_, err := o.calculate(context.Background(), "", models.ModeDecimal, "+", x, y)
+	assert.ErrorIs(t, err, models.ErrModeNotSupported)
+}
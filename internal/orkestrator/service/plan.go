@@ -0,0 +1,266 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/expr"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+)
+
+// операнд шага: известное значение или результат другого шага
+type operand struct {
+	value numeric.Value
+	// индекс шага, результат которого нужен. -1 - значение уже известно
+	step int
+	// результат шага берется с обратным знаком (унарный минус вычисляется на месте)
+	neg bool
+}
+
+// шаг плана: одна операция, которая отправляется агенту
+type step struct {
+	op   string
+	args [2]operand
+	// шаги, которым нужен результат этого шага
+	dependents []int
+}
+
+// граф зависимостей выражения. шаги, операнды которых известны, можно
+// отправлять агентам одновременно, поэтому время вычисления определяется
+// самой длинной цепочкой зависимых операций, а не их общим числом
+type plan struct {
+	steps []*step
+	root  operand
+	// режим вычисления (см. models.Mode*)
+	mode string
+	// точные значения ссылок (только при построении плана)
+	refs map[string]numeric.Value
+}
+
+// функция для построения плана по синтаксическому дереву.
+// bindings - значения идентификаторов и ссылок (см. expr.Bind), refs - точные значения
+// ссылок @<id> в режиме mode (см. Orkestrator.referencedValue), mode - режим вычисления
+func buildPlan(node expr.Node, bindings map[string]float64, refs map[string]numeric.Value, mode string) (*plan, error) {
+	p := &plan{mode: mode, refs: refs}
+	root, err := p.add(node, bindings)
+	if err != nil {
+		return nil, err
+	}
+	p.root = root
+	return p, nil
+}
+
+// функция для добавления узла в план. возвращает операнд с результатом узла
+func (p *plan) add(node expr.Node, bindings map[string]float64) (operand, error) {
+	switch n := node.(type) {
+	case *expr.Number:
+		if n.Imag && p.mode != models.ModeComplex {
+			return operand{}, models.ErrImaginaryNumber
+		}
+		if p.mode == models.ModeFloat {
+			return value(numeric.Value{Float: n.Value}), nil
+		}
+		v, err := numeric.Literal(p.mode, n.Text)
+		if err != nil {
+			return operand{}, err
+		}
+		return value(v), nil
+
+	case *expr.Ident:
+		return value(numeric.FromFloat(p.mode, bindings[n.Name])), nil
+
+	case *expr.Ref:
+		// результат другого выражения переносится точно: @<id> от 1/3 - ровно 1/3
+		if v, ok := p.refs[n.Key()]; ok {
+			return value(v), nil
+		}
+		return value(numeric.FromFloat(p.mode, bindings[n.Key()])), nil
+
+	case *expr.Paren:
+		return p.add(n.X, bindings)
+
+	case *expr.Unary:
+		x, err := p.add(n.X, bindings)
+		if err != nil {
+			return operand{}, err
+		}
+		if n.Op == "-" {
+			x.value = numeric.Neg(p.mode, x.value)
+			x.neg = !x.neg
+		}
+		return x, nil
+
+	case *expr.Binary:
+		x, err := p.add(n.X, bindings)
+		if err != nil {
+			return operand{}, err
+		}
+		y, err := p.add(n.Y, bindings)
+		if err != nil {
+			return operand{}, err
+		}
+		return p.push(n.Op, x, y), nil
+
+	case *expr.Call:
+		args := make([]operand, 0, len(n.Args))
+		for _, arg := range n.Args {
+			x, err := p.add(arg, bindings)
+			if err != nil {
+				return operand{}, err
+			}
+			args = append(args, x)
+		}
+
+		// функции с переменным числом аргументов вычисляются попарно: max(a, b, c) = max(max(a, b), c)
+		if expr.Functions[n.Name].Variadic() {
+			res := args[0]
+			for _, y := range args[1:] {
+				res = p.push(n.Name, res, y)
+			}
+			return res, nil
+		}
+		return p.push(n.Name, args[0], value(numeric.FromFloat(p.mode, 0))), nil
+	}
+
+	return operand{}, models.ErrBadExpression
+}
+
+// функция для добавления шага. возвращает операнд, ссылающийся на его результат
+func (p *plan) push(op string, x, y operand) operand {
+	id := len(p.steps)
+	p.steps = append(p.steps, &step{op: op, args: [2]operand{x, y}})
+
+	for _, arg := range []operand{x, y} {
+		if arg.step >= 0 {
+			p.steps[arg.step].dependents = append(p.steps[arg.step].dependents, id)
+		}
+	}
+	return operand{step: id}
+}
+
+func value(v numeric.Value) operand {
+	return operand{value: v, step: -1}
+}
+
+// число операндов шага, которые ждут результатов еще не вычисленных шагов.
+// known - вычислены ли шаги
+func (s *step) waiting(known []bool) int {
+	n := 0
+	for _, arg := range s.args {
+		if arg.step >= 0 && !known[arg.step] {
+			n++
+		}
+	}
+	return n
+}
+
+// значение операнда по результатам выполненных шагов
+func (p *plan) resolve(a operand, results []numeric.Value) numeric.Value {
+	if a.step < 0 {
+		return a.value
+	}
+	if a.neg {
+		return numeric.Neg(p.mode, results[a.step])
+	}
+	return results[a.step]
+}
+
+// описание вычисленных шагов: операция, аргументы, результат и его источник.
+// шаг без источника не вычислен и не описывается
+func (p *plan) describe(results []numeric.Value, sources []string) []models.Step {
+	var steps []models.Step
+	for i, s := range p.steps {
+		if sources[i] == "" {
+			continue
+		}
+		steps = append(steps, models.Step{
+			Step:      i,
+			Operation: s.op,
+			Arg1:      p.resolve(s.args[0], results).String(),
+			Arg2:      p.resolve(s.args[1], results).String(),
+			Result:    results[i].String(),
+			Source:    sources[i],
+		})
+	}
+	return steps
+}
+
+// запись плана в JSON для хранения в БД: по ней вычисление выражения
+// продолжается после перезапуска оркестратора (см. Orkestrator.Recover)
+type planJSON struct {
+	Mode  string      `json:"mode"`
+	Steps []stepJSON  `json:"steps"`
+	Root  operandJSON `json:"root"`
+}
+
+type stepJSON struct {
+	Op   string         `json:"op"`
+	Args [2]operandJSON `json:"args"`
+}
+
+type operandJSON struct {
+	Value *numeric.Value `json:"value,omitempty"`
+	Step  int            `json:"step"`
+	Neg   bool           `json:"neg,omitempty"`
+}
+
+func (a operand) toJSON() operandJSON {
+	if a.step < 0 {
+		v := a.value
+		return operandJSON{Value: &v, Step: -1, Neg: a.neg}
+	}
+	return operandJSON{Step: a.step, Neg: a.neg}
+}
+
+func (a operandJSON) operand(steps int) (operand, error) {
+	if a.Step < 0 {
+		if a.Value == nil {
+			return operand{}, models.ErrBadExpression
+		}
+		return operand{value: *a.Value, step: -1, neg: a.Neg}, nil
+	}
+	// шаг может ссылаться только на предыдущие шаги
+	if a.Step >= steps {
+		return operand{}, models.ErrBadExpression
+	}
+	return operand{step: a.Step, neg: a.Neg}, nil
+}
+
+func (p *plan) MarshalJSON() ([]byte, error) {
+	data := planJSON{Mode: p.mode, Steps: make([]stepJSON, 0, len(p.steps)), Root: p.root.toJSON()}
+	for _, s := range p.steps {
+		data.Steps = append(data.Steps, stepJSON{Op: s.op, Args: [2]operandJSON{s.args[0].toJSON(), s.args[1].toJSON()}})
+	}
+	return json.Marshal(data)
+}
+
+// восстановление плана из JSON. зависимости шагов строятся заново
+func (p *plan) UnmarshalJSON(b []byte) error {
+	var data planJSON
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	if !numeric.ValidMode(data.Mode) {
+		return models.ErrUnknownMode
+	}
+
+	res := plan{mode: data.Mode}
+	for _, s := range data.Steps {
+		x, err := s.Args[0].operand(len(res.steps))
+		if err != nil {
+			return err
+		}
+		y, err := s.Args[1].operand(len(res.steps))
+		if err != nil {
+			return err
+		}
+		res.push(s.Op, x, y)
+	}
+	root, err := data.Root.operand(len(res.steps))
+	if err != nil {
+		return err
+	}
+	res.root = root
+	*p = res
+	return nil
+}
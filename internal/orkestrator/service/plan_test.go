@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/expr"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+	"github.com/stretchr/testify/assert"
+)
+
+// тесты для построения графа зависимостей
+func TestBuildPlan(t *testing.T) {
+	tests := []struct {
+		name      string
+		expr      string
+		steps     []string
+		ready     int
+		rootValue float64
+	}{
+		{
+			name:      "No operations",
+			expr:      "-(2)",
+			steps:     nil,
+			ready:     0,
+			rootValue: -2,
+		},
+		{
+			name:  "Independent operands",
+			expr:  "(1+2)*(3+4)",
+			steps: []string{"+", "+", "*"},
+			ready: 2,
+		},
+		{
+			name:  "Chain",
+			expr:  "1+2+3+4",
+			steps: []string{"+", "+", "+"},
+			ready: 1,
+		},
+		{
+			name:  "Variadic function",
+			expr:  "max(1, 2+3, x)",
+			steps: []string{"+", "max", "max"},
+			ready: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := expr.Parse(tt.expr)
+			assert.NoError(t, err)
+
+			p, err := buildPlan(tree, map[string]float64{"x": 1}, nil, models.ModeFloat)
+			assert.NoError(t, err)
+
+			var ops []string
+			ready := 0
+			known := make([]bool, len(p.steps))
+			for _, s := range p.steps {
+				ops = append(ops, s.op)
+				if s.waiting(known) == 0 {
+					ready++
+				}
+			}
+			assert.Equal(t, tt.steps, ops)
+			assert.Equal(t, tt.ready, ready)
+			if len(p.steps) == 0 {
+				assert.Equal(t, tt.rootValue, p.resolve(p.root, nil).Float)
+			}
+		})
+	}
+}
+
+// унарный минус над результатом шага применяется при подстановке результата
+func TestPlan_NegatedOperand(t *testing.T) {
+	tree, err := expr.Parse("2*-(3+4)")
+	assert.NoError(t, err)
+
+	p, err := buildPlan(tree, nil, nil, models.ModeFloat)
+	assert.NoError(t, err)
+	assert.Len(t, p.steps, 2)
+
+	results := []numeric.Value{{Float: 7}, {}}
+	assert.Equal(t, -7.0, p.resolve(p.steps[1].args[1], results).Float)
+	assert.Equal(t, []int{1}, p.steps[0].dependents)
+}
+
+// в режиме decimal литералы и переменные хранятся точной записью
+func TestPlan_Decimal(t *testing.T) {
+	tree, err := expr.Parse("-0.1 + x")
+	assert.NoError(t, err)
+
+	p, err := buildPlan(tree, map[string]float64{"x": 0.2}, nil, models.ModeDecimal)
+	assert.NoError(t, err)
+	assert.Len(t, p.steps, 1)
+	assert.Equal(t, "-0.1", p.steps[0].args[0].value.Exact)
+	assert.Equal(t, "0.2", p.steps[0].args[1].value.Exact)
+
+	results := []numeric.Value{{Float: 0.1, Exact: "0.1"}}
+	assert.Equal(t, "-0.1", p.resolve(operand{step: 0, neg: true}, results).Exact)
+}
+
+// план, сохраненный в JSON, восстанавливается с теми же шагами и зависимостями
+func TestPlan_JSON(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		mode string
+	}{
+		{name: "Float", expr: "2*-(3+4) + max(1, x)", mode: models.ModeFloat},
+		{name: "Decimal", expr: "-0.1 + x", mode: models.ModeDecimal},
+		{name: "Complex", expr: "(1+2i)*x", mode: models.ModeComplex},
+		{name: "No operations", expr: "-x", mode: models.ModeFloat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := expr.Parse(tt.expr)
+			assert.NoError(t, err)
+			p, err := buildPlan(tree, map[string]float64{"x": 0.2}, nil, tt.mode)
+			assert.NoError(t, err)
+
+			data, err := json.Marshal(p)
+			assert.NoError(t, err)
+			restored := &plan{}
+			assert.NoError(t, json.Unmarshal(data, restored))
+
+			assert.Equal(t, p, restored)
+		})
+	}
+
+	// шаг не может ссылаться на следующие шаги
+	err := json.Unmarshal([]byte(`{"mode":"float","steps":[{"op":"+","args":[{"step":1},{"step":-1,"value":{"float":1}}]}],"root":{"step":0}}`), &plan{})
+	assert.ErrorIs(t, err, models.ErrBadExpression)
+	err = json.Unmarshal([]byte(`{"mode":"unknown","steps":[],"root":{"step":-1,"value":{"float":1}}}`), &plan{})
+	assert.ErrorIs(t, err, models.ErrUnknownMode)
+}
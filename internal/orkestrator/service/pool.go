@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+)
+
+// агент, к которому оркестратор отправляет операции
+type agentConn struct {
+	addr   string
+	client pb.CalcServiceClient
+
+	// число операций, отправленных агенту и ожидающих ответа
+	inflight int
+	// число неудачных вызовов подряд
+	failures int
+	// до этого момента агент считается недоступным и не получает операций
+	ejectedUntil time.Time
+}
+
+// пул агентов. операция отправляется наименее загруженному доступному агенту
+// (при равной загрузке - по кругу). агент, у которого maxFailures вызовов подряд
+// завершились ошибкой соединения, исключается на ejectTime. операция, которую
+// не удалось отправить агенту или которую агент не принял из-за заполненной
+// очереди, повторяется на другом агенте
+type agentPool struct {
+	mu     sync.Mutex
+	agents []*agentConn
+	next   int
+
+	maxFailures int
+	ejectTime   time.Duration
+	log         *zap.Logger
+}
+
+func newAgentPool(maxFailures int, ejectTime time.Duration, logger *zap.Logger) *agentPool {
+	if maxFailures <= 0 {
+		maxFailures = 1
+	}
+	return &agentPool{
+		maxFailures: maxFailures,
+		ejectTime:   ejectTime,
+		log:         logger,
+	}
+}
+
+// функция для добавления агента в пул. агент с уже известным адресом не добавляется
+func (p *agentPool) add(addr string, client pb.CalcServiceClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range p.agents {
+		if a.addr == addr {
+			return
+		}
+	}
+	p.agents = append(p.agents, &agentConn{addr: addr, client: client})
+}
+
+// агент с таким адресом уже есть в пуле
+func (p *agentPool) has(addr string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, a := range p.agents {
+		if a.addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// функция для удаления агента из пула. операции, уже отправленные агенту, дожидаются ответа
+func (p *agentPool) remove(addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.agents = slices.DeleteFunc(p.agents, func(a *agentConn) bool {
+		return a.addr == addr
+	})
+	if len(p.agents) > 0 {
+		p.next %= len(p.agents)
+	} else {
+		p.next = 0
+	}
+}
+
+// функция для выбора агента среди доступных и еще не опробованных
+func (p *agentPool) pick(tried map[*agentConn]bool) *agentConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best *agentConn
+	for i := range p.agents {
+		a := p.agents[(p.next+i)%len(p.agents)]
+		if tried[a] || now.Before(a.ejectedUntil) {
+			continue
+		}
+		if best == nil || a.inflight < best.inflight {
+			best = a
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	p.next = (p.next + 1) % len(p.agents)
+	best.inflight++
+	return best
+}
+
+// функция для учета результата вызова агента
+func (p *agentPool) release(a *agentConn, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	a.inflight--
+	if !agentFailure(err) {
+		a.failures = 0
+		return
+	}
+
+	a.failures++
+	if a.failures >= p.maxFailures {
+		a.failures = 0
+		a.ejectedUntil = time.Now().Add(p.ejectTime)
+		p.log.Warn("agent " + a.addr + " is unavailable, ejected for " + p.ejectTime.String())
+	}
+}
+
+// число агентов, которым сейчас можно отправлять операции
+func (p *agentPool) healthy() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for _, a := range p.agents {
+		if !now.Before(a.ejectedUntil) {
+			n++
+		}
+	}
+	return n
+}
+
+// функция для вычисления операции одним из агентов пула
+func (p *agentPool) calculate(ctx context.Context, req *pb.TaskRequest) (*pb.ResResponse, error) {
+	tried := make(map[*agentConn]bool)
+	var lastErr error
+
+	for {
+		a := p.pick(tried)
+		if a == nil {
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w: %w", models.ErrNoAgents, lastErr)
+			}
+			return nil, models.ErrNoAgents
+		}
+		tried[a] = true
+
+		resp, err := a.client.Calculation(ctx, req)
+		p.release(a, err)
+		if err == nil {
+			return resp, nil
+		}
+		if !agentFailure(err) && !agentBusy(err) {
+			return nil, calcError(err)
+		}
+
+		p.log.Warn("agent " + a.addr + ": " + err.Error())
+		lastErr = err
+	}
+}
+
+// ошибка соединения с агентом (в отличие от ошибки вычисления, операцию можно повторить на другом агенте)
+func agentFailure(err error) bool {
+	return err != nil && status.Code(err) == codes.Unavailable
+}
+
+// очередь агента заполнена: агент доступен, но операцию нужно отправить другому агенту
+func agentBusy(err error) bool {
+	return status.Code(err) == codes.ResourceExhausted
+}
+
+// функция для восстановления ошибки вычисления из ответа агента
+func calcError(err error) error {
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unknown {
+		return models.ErrorFromText(s.Message())
+	}
+	return err
+}
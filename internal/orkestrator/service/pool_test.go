@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	agentconfig "github.com/ArtemiySps/calc_go_final/internal/agent/config"
+	agent "github.com/ArtemiySps/calc_go_final/internal/agent/service"
+	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// агент, запущенный в тесте. calls - число принятых вызовов
+type testAgent struct {
+	addr   string
+	server *grpc.Server
+	calls  atomic.Int32
+}
+
+func startTestAgent(t *testing.T) *testAgent {
+	return startTestAgentWithTimes(t, map[string]int{"+": 0, "-": 0, "*": 0, "/": 0, "%": 0, "sqrt": 0})
+}
+
+// агент с заданным временем выполнения операций (мс)
+func startTestAgentWithTimes(t *testing.T, times map[string]int) *testAgent {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta := &testAgent{addr: lis.Addr().String()}
+	ta.server = grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ta.calls.Add(1)
+		return handler(ctx, req)
+	}))
+
+	cfg := &agentconfig.Config{
+		ComputingPower: 1,
+		OperationTimes: times,
+	}
+	pb.RegisterCalcServiceServer(ta.server, agent.NewAgent(cfg, zap.NewNop()))
+
+	go ta.server.Serve(lis)
+	t.Cleanup(ta.server.Stop)
+	return ta
+}
+
+// операции распределяются между агентами, а после остановки одного агента
+// вычисления продолжаются на оставшемся
+func TestOrkestrator_AgentPool_Failover(t *testing.T) {
+	first := startTestAgent(t)
+	second := startTestAgent(t)
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			AgentAddrs:       []string{first.addr, second.addr},
+			AgentMaxFailures: 1,
+			AgentEjectTime:   60000,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "1+2+3+4"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 10.0, res.Result)
+	assert.Equal(t, int32(3), first.calls.Load()+second.calls.Load())
+	assert.Positive(t, first.calls.Load())
+	assert.Positive(t, second.calls.Load())
+
+	first.server.Stop()
+	before := second.calls.Load()
+
+	res, err = o.ExpressionOperations(models.CalcRequest{Expression: "10-4-3"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, res.Result)
+	assert.Equal(t, before+2, second.calls.Load())
+	assert.Equal(t, 1, o.agents.healthy())
+
+	// ошибка вычисления не исключает агента и не повторяется на другом
+	_, err = o.ExpressionOperations(models.CalcRequest{Expression: "1%0"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrModuloByZero)
+	assert.Equal(t, 1, o.agents.healthy())
+
+	second.server.Stop()
+	_, err = o.ExpressionOperations(models.CalcRequest{Expression: "1+1"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrNoAgents)
+	assert.Equal(t, 0, o.agents.healthy())
+}
+
+// клиент, который возвращает заданную ошибку
+type failingClient struct {
+	pb.CalcServiceClient
+	err   error
+	calls int
+}
+
+func (c *failingClient) Calculation(ctx context.Context, in *pb.TaskRequest, opts ...grpc.CallOption) (*pb.ResResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &pb.ResResponse{Res: in.Arg1 + in.Arg2}, nil
+}
+
+// тесты для выбора агента: наименее загруженный, исключение после maxFailures
+// ошибок подряд и возвращение после ejectTime
+func TestAgentPool(t *testing.T) {
+	down := &failingClient{err: status.Error(codes.Unavailable, "connection refused")}
+	up := &failingClient{}
+
+	p := newAgentPool(2, 50*time.Millisecond, zap.NewNop())
+	p.add("down", down)
+	p.add("up", up)
+
+	busy := p.pick(nil)
+	assert.Equal(t, "down", busy.addr)
+	assert.Equal(t, "up", p.pick(nil).addr)
+	assert.Equal(t, "up", p.pick(map[*agentConn]bool{busy: true}).addr)
+	p.release(busy, nil)
+	p.release(p.agents[1], nil)
+	p.release(p.agents[1], nil)
+
+	// неудачный вызов повторяется на другом агенте, после второй ошибки подряд агент исключается
+	for range 4 {
+		resp, err := p.calculate(context.Background(), &pb.TaskRequest{Opr: "+", Arg1: 1, Arg2: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(3), resp.Res)
+	}
+	assert.Equal(t, 2, down.calls)
+	assert.Equal(t, 1, p.healthy())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, 2, p.healthy())
+
+	// ошибка вычисления передается как есть
+	up.err = status.Error(codes.Unknown, models.ErrDivisionByZero.Error())
+	down.err = up.err
+	_, err := p.calculate(context.Background(), &pb.TaskRequest{Opr: "/", Arg1: 1})
+	assert.ErrorIs(t, err, models.ErrDivisionByZero)
+}
+
+// операция, которую агент не принял из-за заполненной очереди, отправляется
+// другому агенту, а сам агент не исключается из пула
+func TestAgentPool_Busy(t *testing.T) {
+	busy := &failingClient{err: status.Error(codes.ResourceExhausted, models.ErrAgentBusy.Error())}
+	free := &failingClient{}
+
+	p := newAgentPool(1, time.Minute, zap.NewNop())
+	p.add("busy", busy)
+	p.add("free", free)
+
+	for range 2 {
+		resp, err := p.calculate(context.Background(), &pb.TaskRequest{Opr: "+", Arg1: 1, Arg2: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, float32(3), resp.Res)
+	}
+	assert.Equal(t, 2, p.healthy())
+
+	// если заняты все агенты, возвращается ошибка no available agents
+	free.err = busy.err
+	_, err := p.calculate(context.Background(), &pb.TaskRequest{Opr: "+", Arg1: 1, Arg2: 2})
+	assert.ErrorIs(t, err, models.ErrNoAgents)
+	assert.Contains(t, err.Error(), models.ErrAgentBusy.Error())
+	assert.Equal(t, 2, p.healthy())
+}
+
+// регрессия точности: числа передаются агенту и обратно как float64
+func TestOrkestrator_ExpressionOperations_Precision(t *testing.T) {
+	ta := startTestAgent(t)
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{AgentAddrs: []string{ta.addr}},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	price, tax := 19.99, 0.01
+	amount, hundred, whole := 1234567.89, 100.0, 123456789.0
+	tests := []struct {
+		expr     string
+		expected float64
+	}{
+		{expr: "16777217+1", expected: 16777218},
+		{expr: "19.99+0.01", expected: price + tax},
+		{expr: "1234567.89*100-123456789", expected: amount*hundred - whole},
+		{expr: "0.1+0.2", expected: 0.30000000000000004},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			res, err := o.ExpressionOperations(models.CalcRequest{Expression: tt.expr}, "testuser")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, res.Result)
+		})
+	}
+}
+
+// в режиме decimal агент вычисляет операции над точной записью чисел
+func TestOrkestrator_ExpressionOperations_Decimal(t *testing.T) {
+	ta := startTestAgent(t)
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			AgentAddrs:      []string{ta.addr},
+			NumericMode:     models.ModeFloat,
+			DecimalScale:    5,
+			DecimalRounding: models.RoundHalfUp,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	tests := []struct {
+		expr     string
+		vars     map[string]float64
+		expected string
+	}{
+		{expr: "0.1+0.2", expected: "0.3"},
+		{expr: "2/3", expected: "0.66667"},
+		{expr: "1.10*3-0.3", expected: "3"},
+		{expr: "-x*100", vars: map[string]float64{"x": 0.07}, expected: "-7"},
+		{expr: "99999999999999999999+1", expected: "100000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			res, err := o.ExpressionOperations(models.CalcRequest{
+				Expression: tt.expr,
+				Variables:  tt.vars,
+				Mode:       models.ModeDecimal,
+			}, "testuser")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, res.Value)
+		})
+	}
+
+	// точная запись и режим сохраняются вместе с результатом
+	id, err := o.SubmitExpression(models.CalcRequest{Expression: "0.1+0.2", Mode: models.ModeDecimal}, "testuser")
+	assert.NoError(t, err)
+	waitJob(o, id)
+	e, err := o.GetExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCompleted, e.Status)
+	assert.Equal(t, models.ModeDecimal, e.Mode)
+	assert.Equal(t, "0.3", e.Value)
+	assert.Equal(t, 0.3, e.Result)
+
+	_, err = o.ExpressionOperations(models.CalcRequest{Expression: "1+1", Mode: "binary"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrUnknownMode)
+}
+
+// в режиме rational результат - несократимая дробь с десятичным приближением
+func TestOrkestrator_ExpressionOperations_Rational(t *testing.T) {
+	ta := startTestAgent(t)
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			AgentAddrs:      []string{ta.addr},
+			NumericMode:     models.ModeRational,
+			DecimalScale:    4,
+			DecimalRounding: models.RoundHalfEven,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "1/3+1/6"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ModeRational, res.Mode)
+	assert.Equal(t, "1/2", res.Value)
+	assert.Equal(t, &models.Fraction{Numerator: "1", Denominator: "2"}, res.Fraction)
+	assert.Equal(t, "0.5", res.Decimal)
+	assert.Equal(t, 0.5, res.Result)
+
+	res, err = o.ExpressionOperations(models.CalcRequest{Expression: "2/3*-x", Variables: map[string]float64{"x": 0.5}}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, "-1/3", res.Value)
+	assert.Equal(t, "-0.3333", res.Decimal)
+
+	// дробь сохраняется вместе с выражением
+	stored, err := o.GetExpression(res.ID, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, "-1/3", stored.Value)
+	assert.Equal(t, &models.Fraction{Numerator: "-1", Denominator: "3"}, stored.Fraction)
+	assert.Equal(t, "-0.3333", stored.Decimal)
+}
+
+// в режиме complex операции вычисляются над комплексными числами
+func TestOrkestrator_ExpressionOperations_Complex(t *testing.T) {
+	ta := startTestAgent(t)
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{AgentAddrs: []string{ta.addr}},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+
+	tests := []struct {
+		expr     string
+		expected models.Complex
+	}{
+		{expr: "2i*(1-i)", expected: models.Complex{Real: 2, Imag: 2}},
+		{expr: "sqrt(-4)", expected: models.Complex{Real: 0, Imag: 2}},
+		{expr: "-(3+x)", expected: models.Complex{Real: -4.5, Imag: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			res, err := o.ExpressionOperations(models.CalcRequest{
+				Expression: tt.expr,
+				Variables:  map[string]float64{"x": 1.5},
+				Mode:       models.ModeComplex,
+			}, "testuser")
+			assert.NoError(t, err)
+			assert.Equal(t, &tt.expected, res.Complex)
+			assert.Equal(t, tt.expected.Real, res.Result)
+
+			stored, err := o.GetExpression(res.ID, "testuser")
+			assert.NoError(t, err)
+			assert.Equal(t, res.Value, stored.Value)
+			assert.Equal(t, &tt.expected, stored.Complex)
+		})
+	}
+
+	// мнимые числа вне режима complex - ошибка разбора
+	_, err := o.ExpressionOperations(models.CalcRequest{Expression: "sqrt(-4)+2i"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrImaginaryNumber)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// очередь задач для агентов, которые сами забирают задачи (GetTask) и возвращают
+// результаты (SubmitResult). нулевое значение готово к использованию
+type taskQueue struct {
+	mu    sync.Mutex
+	ready []*models.Task
+	// задачи, ожидающие результата (в очереди или выданные агентам)
+	waiting map[string]*queuedTask
+}
+
+// задача, ожидающая результата
+type queuedTask struct {
+	done chan models.Task
+	// закрывается, когда агент впервые забрал задачу (см. take)
+	taken    chan struct{}
+	wasTaken bool
+}
+
+// функция для добавления задачи в очередь. результат придет в канал done,
+// а taken закроется, когда агент заберет задачу
+func (q *taskQueue) push(t *models.Task) (done <-chan models.Task, taken <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiting == nil {
+		q.waiting = make(map[string]*queuedTask)
+	}
+	w := &queuedTask{done: make(chan models.Task, 1), taken: make(chan struct{})}
+	q.waiting[t.ID] = w
+	q.ready = append(q.ready, t)
+	return w.done, w.taken
+}
+
+// функция для отметки о том, что агент забрал задачу и начал ее вычислять.
+// после возвращения задачи в очередь (requeue) отметка остается
+func (q *taskQueue) take(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if w, ok := q.waiting[id]; ok && !w.wasTaken {
+		w.wasTaken = true
+		close(w.taken)
+	}
+}
+
+// функция для получения первой готовой задачи. задачи, результат которых
+// уже получен (например, возвращенные в очередь после потери агента), пропускаются
+func (q *taskQueue) pop() (*models.Task, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.ready) > 0 {
+		t := q.ready[0]
+		q.ready[0] = nil
+		q.ready = q.ready[1:]
+		if _, ok := q.waiting[t.ID]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// функция для возвращения выданной задачи в начало очереди
+func (q *taskQueue) requeue(t *models.Task) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.waiting[t.ID]; !ok {
+		return
+	}
+	q.ready = append([]*models.Task{t}, q.ready...)
+}
+
+// функция для записи результата задачи
+func (q *taskQueue) complete(t models.Task) error {
+	q.mu.Lock()
+	w, ok := q.waiting[t.ID]
+	delete(q.waiting, t.ID)
+	q.mu.Unlock()
+
+	if !ok {
+		return models.ErrTaskNotFound
+	}
+	w.done <- t
+	return nil
+}
+
+// функция для удаления задачи, результат которой больше не нужен (вычисление отменено).
+// если задача уже выдана агенту, ее результат не будет принят
+func (q *taskQueue) drop(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.waiting, id)
+	q.ready = slices.DeleteFunc(q.ready, func(t *models.Task) bool {
+		return t.ID == id
+	})
+}
+
+// число задач, ожидающих выдачи агентам
+func (q *taskQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+)
+
+const stepsTable = `
+CREATE TABLE IF NOT EXISTS expression_steps(
+	id TEXT NOT NULL,
+	step INTEGER NOT NULL,
+	value TEXT NOT NULL,
+	source TEXT,
+	PRIMARY KEY (id, step)
+);`
+
+// столбцы, добавленные в таблицу результатов шагов после первой версии
+var stepsColumns = []column{
+	{name: "source", def: "TEXT"},
+}
+
+// создание таблицы результатов шагов вычисляющихся выражений
+func createStepsTable(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, stepsTable); err != nil {
+		return err
+	}
+	return addColumns(ctx, db, "expression_steps", stepsColumns)
+}
+
+// ключ операции: ID выражения и номер шага плана. одна и та же операция
+// после перезапуска оркестратора или повтора получает тот же ключ
+func stepKey(id string, step int) string {
+	return id + ":" + strconv.Itoa(step)
+}
+
+// сохранить результат шага выражения и его источник (см. models.Source*). повторный результат
+// того же шага (например, от агента, вычислявшего его до перезапуска) не перезаписывает первый
+func (o *Orkestrator) SaveStepResult(id string, step int, res numeric.Value, source string) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	var q = "INSERT OR IGNORE INTO expression_steps (id, step, value, source) values ($1, $2, $3, $4)"
+	_, err = o.exprs.ExecContext(o.ctx, q, id, step, string(data), source)
+	return err
+}
+
+// получить сохраненные результаты шагов выражения
+func (o *Orkestrator) stepResults(id string) (map[int]stepResult, error) {
+	var q = "SELECT step, value, source FROM expression_steps WHERE id = $1"
+	rows, err := o.exprs.QueryContext(o.ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make(map[int]stepResult)
+	for rows.Next() {
+		var r stepResult
+		var data string
+		var source sql.NullString
+		if err := rows.Scan(&r.step, &data, &source); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &r.res); err != nil {
+			return nil, err
+		}
+		// результаты, сохраненные без источника, вычислены агентами
+		r.source = source.String
+		if r.source == "" {
+			r.source = models.SourceAgent
+		}
+		results[r.step] = r
+	}
+	return results, rows.Err()
+}
+
+// удалить результаты шагов выражения (после его вычисления)
+func (o *Orkestrator) DeleteStepResults(id string) error {
+	var q = "DELETE FROM expression_steps WHERE id = $1"
+	_, err := o.exprs.ExecContext(o.ctx, q, id)
+	return err
+}
+
+// выражение, которое вычислялось до перезапуска оркестратора
+type pendingExpression struct {
+	id        string
+	user      string
+	expr      string
+	variables sql.NullString
+	mode      sql.NullString
+	plan      sql.NullString
+	attempts  sql.NullInt64
+	noCache   sql.NullBool
+}
+
+// продолжение вычисления выражений со статусом pending после перезапуска оркестратора.
+// выражение с сохраненным планом продолжается с невычисленных шагов, без плана -
+// разбирается и вычисляется заново с сохраненными переменными. выражение, которое
+// восстановить нельзя, получает статус failed. выражения, которые уже вычисляются,
+// не перезапускаются. вызывается до запуска серверов: восстановленные выражения сразу
+// попадают в планировщик (их можно отменить), а вычисляются через delay, чтобы агенты
+// успели заново зарегистрироваться. возвращает число продолженных выражений
+func (o *Orkestrator) Recover(delay time.Duration) (int, error) {
+	// результаты шагов уже завершенных выражений (оркестратор остановился до их удаления)
+	var q = "DELETE FROM expression_steps WHERE id NOT IN (SELECT id FROM expressions WHERE status = $1)"
+	if _, err := o.exprs.ExecContext(o.ctx, q, models.StatusPending); err != nil {
+		return 0, err
+	}
+
+	pending, err := o.pendingExpressions()
+	if err != nil {
+		return 0, err
+	}
+
+	resumed := 0
+	for _, e := range pending {
+		if _, ok := o.sched.get(e.id); ok {
+			continue
+		}
+		j, err := o.restore(e)
+		if err != nil {
+			o.log.Error(e.id + ": expression is not recovered: " + err.Error())
+			o.ChangeExpressionStatus(e.id, 0, false, err.Error())
+			continue
+		}
+		o.log.Info(fmt.Sprintf("%s: expression resumed, %d of %d steps already calculated", e.id, len(j.results), len(j.plan.steps)))
+
+		o.sched.start(j, func(j *job) {
+			select {
+			case <-j.ctx.Done():
+			case <-time.After(delay):
+			}
+			o.run(j)
+		})
+		resumed++
+	}
+	return resumed, nil
+}
+
+// выражения со статусом pending всех пользователей
+func (o *Orkestrator) pendingExpressions() ([]pendingExpression, error) {
+	var q = "SELECT id, user, expr, variables, mode, plan, attempts, no_cache FROM expressions WHERE status = $1"
+	rows, err := o.exprs.QueryContext(o.ctx, q, models.StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []pendingExpression
+	for rows.Next() {
+		var e pendingExpression
+		if err := rows.Scan(&e.id, &e.user, &e.expr, &e.variables, &e.mode, &e.plan, &e.attempts, &e.noCache); err != nil {
+			return nil, err
+		}
+		pending = append(pending, e)
+	}
+	return pending, rows.Err()
+}
+
+// задача вычисления для выражения, которое вычислялось до перезапуска
+func (o *Orkestrator) restore(e pendingExpression) (*job, error) {
+	bindings, err := decodeVariables(e.variables)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &plan{}
+	if e.plan.Valid && e.plan.String != "" {
+		if err := json.Unmarshal([]byte(e.plan.String), p); err != nil {
+			return nil, err
+		}
+	} else {
+		// оркестратор остановился до сохранения плана
+		mode := o.modeOrDefault(e.mode.String)
+		if !numeric.ValidMode(mode) {
+			return nil, models.ErrUnknownMode
+		}
+		p, bindings, err = o.compile(e.expr, mode, bindings, e.user)
+		if err != nil {
+			return nil, err
+		}
+		if err := o.SetExpressionMode(e.id, mode); err != nil {
+			return nil, err
+		}
+		if err := o.SetExpressionVariables(e.id, bindings); err != nil {
+			return nil, err
+		}
+		if err := o.SetExpressionPlan(e.id, p); err != nil {
+			o.log.Warn(e.id + ": plan is not saved: " + err.Error())
+		}
+	}
+
+	results, err := o.stepResults(e.id)
+	if err != nil {
+		return nil, err
+	}
+	for step := range results {
+		if step < 0 || step >= len(p.steps) {
+			delete(results, step)
+		}
+	}
+
+	j := newJob(e.id, e.user, time.Duration(o.Config.ExpressionTimeout)*time.Millisecond)
+	j.plan, j.bindings, j.mode, j.results, j.noCache = p, bindings, p.mode, results, e.noCache.Bool
+	j.attempts.Store(int32(e.attempts.Int64))
+	return j, nil
+}
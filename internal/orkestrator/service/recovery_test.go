@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// выражение в БД в том состоянии, в котором его оставил остановившийся оркестратор
+func addPendingExpression(t *testing.T, o *Orkestrator, expression string, vars map[string]float64, withPlan bool, steps map[int]numeric.Value) string {
+	id, err := o.AddExpressionToStorage(expression, vars, "testuser")
+	assert.NoError(t, err)
+	if !withPlan {
+		return id
+	}
+
+	assert.NoError(t, o.SetExpressionMode(id, models.ModeFloat))
+	p, bindings, err := o.compile(expression, models.ModeFloat, vars, "testuser")
+	assert.NoError(t, err)
+	assert.NoError(t, o.SetExpressionVariables(id, bindings))
+	assert.NoError(t, o.SetExpressionPlan(id, p))
+	for step, res := range steps {
+		assert.NoError(t, o.SaveStepResult(id, step, res, models.SourceAgent))
+	}
+	return id
+}
+
+// агент забирает задачу из очереди и возвращает результат
+func takeTask(t *testing.T, server *TaskServer) *pb.Task {
+	var task *pb.Task
+	assert.Eventually(t, func() bool {
+		var err error
+		task, err = server.GetTask(context.Background(), &pb.GetTaskRequest{})
+		return err == nil
+	}, time.Second, time.Millisecond)
+	return task
+}
+
+// после перезапуска выражение продолжается с невычисленных шагов, а результат,
+// присланный агентом до перезапуска, не вычисляется заново
+func TestOrkestrator_Recover(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{DispatchMode: models.DispatchPull},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+	server := &TaskServer{o: o}
+
+	// (1+2) уже вычислено
+	resumed := addPendingExpression(t, o, "(1+2)*(3+4)", nil, true, map[int]numeric.Value{0: {Float: 3}})
+	// оркестратор остановился до сохранения плана
+	reparsed := addPendingExpression(t, o, "2*x", map[string]float64{"x": 5}, false, nil)
+	broken := addPendingExpression(t, o, "1+", nil, false, nil)
+	// результаты шагов завершенного выражения удаляются
+	completed := addPendingExpression(t, o, "1+1", nil, true, map[int]numeric.Value{0: {Float: 2}})
+	assert.NoError(t, o.ChangeExpressionStatus(completed, 2, true, ""))
+
+	n, err := o.Recover(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	results, err := o.stepResults(completed)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	e, err := o.GetExpression(broken, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusFailed, e.Status)
+
+	// поздний результат уже вычисленного шага не принимается
+	_, err = server.SubmitResult(context.Background(), &pb.TaskResult{Id: stepKey(resumed, 0), Res: 3})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	expected := map[string]float64{stepKey(resumed, 1): 7, stepKey(resumed, 2): 21, stepKey(reparsed, 0): 10}
+	for range expected {
+		task := takeTask(t, server)
+		res, ok := expected[task.Id]
+		assert.True(t, ok, task.Id)
+		if task.Id == stepKey(resumed, 2) {
+			x, y := task.Args()
+			assert.Equal(t, []float64{3, 7}, []float64{x, y})
+		}
+		result := &pb.TaskResult{Id: task.Id}
+		result.SetResult(res)
+		_, err := server.SubmitResult(context.Background(), result)
+		assert.NoError(t, err)
+	}
+	waitJob(o, resumed)
+	waitJob(o, reparsed)
+
+	// шаг, вычисленный до перезапуска, тоже есть среди шагов выражения
+	e, err = o.GetExpression(resumed, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Step{
+		{Step: 0, Operation: "+", Arg1: "1", Arg2: "2", Result: "3", Source: models.SourceAgent},
+		{Step: 1, Operation: "+", Arg1: "3", Arg2: "4", Result: "7", Source: models.SourceAgent},
+		{Step: 2, Operation: "*", Arg1: "3", Arg2: "7", Result: "21", Source: models.SourceAgent},
+	}, e.Steps)
+
+	for id, res := range map[string]float64{resumed: 21, reparsed: 10} {
+		e, err := o.GetExpression(id, "testuser")
+		assert.NoError(t, err)
+		assert.Equal(t, models.StatusCompleted, e.Status)
+		assert.Equal(t, res, e.Result)
+
+		results, err := o.stepResults(id)
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+	}
+}
+
+// повторный результат шага не перезаписывает сохраненный
+func TestOrkestrator_SaveStepResult(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{exprs: db, ctx: context.Background()}
+
+	assert.NoError(t, o.SaveStepResult("id", 0, numeric.Value{Float: 0.5, Exact: "1/2"}, models.SourceAgent))
+	assert.NoError(t, o.SaveStepResult("id", 0, numeric.Value{Float: 1}, models.SourceCache))
+	assert.NoError(t, o.SaveStepResult("id", 1, numeric.Value{Float: 1, Imag: 2}, models.SourceCache))
+
+	results, err := o.stepResults("id")
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]stepResult{
+		0: {step: 0, res: numeric.Value{Float: 0.5, Exact: "1/2"}, source: models.SourceAgent},
+		1: {step: 1, res: numeric.Value{Float: 1, Imag: 2}, source: models.SourceCache},
+	}, results)
+
+	assert.NoError(t, o.DeleteStepResults("id"))
+	results, err = o.stepResults("id")
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+// выражение, которое уже вычисляется, не перезапускается: оно вычисляется один раз,
+// и его задача в очереди не подменяется
+func TestOrkestrator_Recover_Running(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{DispatchMode: models.DispatchPull},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+	server := &TaskServer{o: o}
+
+	id, err := o.SubmitExpression(models.CalcRequest{Expression: "1+2"}, "testuser")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return o.queue.len() == 1 }, time.Second, time.Millisecond)
+	j, ok := o.sched.get(id)
+	assert.True(t, ok)
+
+	n, err := o.Recover(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 1, o.queue.len())
+	running, _ := o.sched.get(id)
+	assert.Same(t, j, running)
+
+	task := takeTask(t, server)
+	result := &pb.TaskResult{Id: task.Id}
+	result.SetResult(3)
+	_, err = server.SubmitResult(context.Background(), result)
+	assert.NoError(t, err)
+	waitJob(o, id)
+
+	e, err := o.GetExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCompleted, e.Status)
+	assert.Equal(t, 3.0, e.Result)
+	assert.Equal(t, 0, o.queue.len())
+}
+
+// восстановленное выражение вычисляется через delay, а до этого его можно отменить
+func TestOrkestrator_Recover_Delay(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{DispatchMode: models.DispatchPull},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+
+	id := addPendingExpression(t, o, "1+2", nil, true, nil)
+	n, err := o.Recover(time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 0, o.queue.len())
+
+	e, err := o.CancelExpression(id, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, e.Status)
+}
+
+// флаг no_cache сохраняется вместе с выражением и восстанавливается после перезапуска
+func TestOrkestrator_Recover_NoCache(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+
+	for _, noCache := range []bool{true, false} {
+		j, err := o.prepare(models.CalcRequest{Expression: "1+2", NoCache: noCache}, "testuser")
+		assert.NoError(t, err)
+
+		pending, err := o.pendingExpressions()
+		assert.NoError(t, err)
+		if assert.Len(t, pending, 1) {
+			restored, err := o.restore(pending[0])
+			assert.NoError(t, err)
+			assert.Equal(t, noCache, restored.noCache)
+		}
+		assert.NoError(t, o.ChangeExpressionStatus(j.id, 3, true, ""))
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// зарегистрированный агент
+type agentInfo struct {
+	models.Agent
+
+	lastSeen time.Time
+	// задачи из очереди, выданные агенту и ожидающие результата
+	tasks map[string]*models.Task
+}
+
+// реестр живых агентов. агент, который не присылал Heartbeat дольше lostAfter,
+// считается потерянным: он удаляется из реестра и пула, а его задачи возвращаются в очередь
+type registry struct {
+	mu     sync.Mutex
+	agents map[string]*agentInfo
+}
+
+// функция для добавления агента. возвращает его ID
+func (r *registry) register(a models.Agent) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.agents == nil {
+		r.agents = make(map[string]*agentInfo)
+	}
+	a.ID = models.MakeID()
+	r.agents[a.ID] = &agentInfo{
+		Agent:    a,
+		lastSeen: time.Now(),
+		tasks:    make(map[string]*models.Task),
+	}
+	return a.ID
+}
+
+// функция для отметки о том, что агент жив, и записи его загрузки
+func (r *registry) heartbeat(id string, load models.AgentLoad) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.agents[id]
+	if !ok {
+		return models.ErrAgentNotFound
+	}
+	a.lastSeen = time.Now()
+	a.Load = load
+	return nil
+}
+
+// зарегистрированные агенты, упорядоченные по ID
+func (r *registry) list() []models.Agent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agents := make([]models.Agent, 0, len(r.agents))
+	for _, a := range r.agents {
+		agents = append(agents, a.Agent)
+	}
+	slices.SortFunc(agents, func(a, b models.Agent) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return agents
+}
+
+// число зарегистрированных агентов
+func (r *registry) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.agents)
+}
+
+// функция для записи задачи, выданной агенту. пустой id - агент без регистрации, задача не отслеживается
+func (r *registry) take(id string, t *models.Task) error {
+	if id == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.agents[id]
+	if !ok {
+		return models.ErrAgentNotFound
+	}
+	a.lastSeen = time.Now()
+	a.tasks[t.ID] = t
+	return nil
+}
+
+// функция для удаления выполненной задачи у агента
+func (r *registry) done(id string, taskID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a, ok := r.agents[id]; ok {
+		delete(a.tasks, taskID)
+	}
+}
+
+// функция для удаления агентов, не присылавших Heartbeat дольше lostAfter
+func (r *registry) expire(lostAfter time.Duration) []*agentInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lost []*agentInfo
+	deadline := time.Now().Add(-lostAfter)
+	for id, a := range r.agents {
+		if a.lastSeen.Before(deadline) {
+			lost = append(lost, a)
+			delete(r.agents, id)
+		}
+	}
+	return lost
+}
+
+// есть живой агент с таким адресом (например, агент перезапустился и зарегистрировался заново)
+func (r *registry) hasAddress(addr string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, a := range r.agents {
+		if a.Address == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// живые агенты и их загрузка из последнего Heartbeat
+func (o *Orkestrator) Agents() []models.Agent {
+	return o.registry.list()
+}
+
+// функция для отслеживания потерянных агентов. проверка выполняется раз в HeartbeatInterval,
+// агент теряется после MaxMissedHeartbeats пропущенных подряд Heartbeat. завершается при отмене ctx
+func (o *Orkestrator) WatchAgents(ctx context.Context) {
+	interval := time.Duration(o.Config.HeartbeatInterval) * time.Millisecond
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.expireAgents()
+		}
+	}
+}
+
+// функция для удаления потерянных агентов и возвращения их задач в очередь
+func (o *Orkestrator) expireAgents() {
+	lostAfter := time.Duration(o.Config.HeartbeatInterval*o.Config.MaxMissedHeartbeats) * time.Millisecond
+
+	for _, a := range o.registry.expire(lostAfter) {
+		o.log.Warn("agent " + a.ID + " is lost")
+		if a.Address != "" && o.agents != nil && !o.registry.hasAddress(a.Address) {
+			o.agents.remove(a.Address)
+		}
+		for _, t := range a.tasks {
+			o.log.Info(t.ID + ": task requeued")
+			o.queue.requeue(t)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// агент с gRPC сервером после регистрации попадает в пул,
+// а после пропущенных Heartbeat удаляется из него
+func TestOrkestrator_RegisterAgent(t *testing.T) {
+	ta := startTestAgent(t)
+
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			DispatchMode:        models.DispatchPush,
+			HeartbeatInterval:   10,
+			MaxMissedHeartbeats: 2,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	assert.NoError(t, o.ConnectToServer())
+	server := &TaskServer{o: o}
+
+	// агентов нет
+	_, err := o.ExpressionOperations(models.CalcRequest{Expression: "1+1"}, "testuser")
+	assert.ErrorIs(t, err, models.ErrNoAgents)
+
+	resp, err := server.Register(context.Background(), &pb.RegisterRequest{
+		Address:        ta.addr,
+		ComputingPower: 1,
+		Operations:     []string{"+"},
+		Version:        "test",
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.AgentId)
+	assert.Equal(t, int32(10), resp.HeartbeatIntervalMs)
+
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "1+1"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, res.Result)
+
+	// пока агент присылает Heartbeat, он не теряется
+	for range 3 {
+		time.Sleep(10 * time.Millisecond)
+		_, err = server.Heartbeat(context.Background(), &pb.HeartbeatRequest{AgentId: resp.AgentId})
+		assert.NoError(t, err)
+		o.expireAgents()
+	}
+	assert.Equal(t, 1, o.agents.healthy())
+
+	// Heartbeat сообщает загрузку агента
+	_, err = server.Heartbeat(context.Background(), &pb.HeartbeatRequest{
+		AgentId: resp.AgentId, Workers: 1, BusyWorkers: 1, QueueDepth: 3, QueueSize: 100,
+	})
+	assert.NoError(t, err)
+	if agents := o.Agents(); assert.Len(t, agents, 1) {
+		assert.Equal(t, models.AgentLoad{Workers: 1, BusyWorkers: 1, QueueDepth: 3, QueueSize: 100}, agents[0].Load)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	o.expireAgents()
+	assert.Equal(t, 0, o.agents.healthy())
+
+	// потерянный агент должен зарегистрироваться заново
+	_, err = server.Heartbeat(context.Background(), &pb.HeartbeatRequest{AgentId: resp.AgentId})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// задачи потерянного агента возвращаются в очередь и достаются другому агенту
+func TestOrkestrator_LostAgentTasksRequeued(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		Config: &config.Config{
+			DispatchMode:        models.DispatchPull,
+			HeartbeatInterval:   10,
+			MaxMissedHeartbeats: 1,
+		},
+		log:   zap.NewNop(),
+		exprs: db,
+		ctx:   context.Background(),
+	}
+	server := &TaskServer{o: o}
+
+	lost, err := server.Register(context.Background(), &pb.RegisterRequest{ComputingPower: 1})
+	assert.NoError(t, err)
+
+	type result struct {
+		res float64
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := o.ExpressionOperations(models.CalcRequest{Expression: "2*3"}, "testuser")
+		done <- result{res.Result, err}
+	}()
+
+	// первый агент забирает задачу и пропадает
+	var task *pb.Task
+	for task == nil {
+		task, _ = server.GetTask(context.Background(), &pb.GetTaskRequest{AgentId: lost.AgentId})
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	alive, err := server.Register(context.Background(), &pb.RegisterRequest{ComputingPower: 1})
+	assert.NoError(t, err)
+	o.expireAgents()
+
+	requeued, err := server.GetTask(context.Background(), &pb.GetTaskRequest{AgentId: alive.AgentId})
+	assert.NoError(t, err)
+	assert.Equal(t, task.Id, requeued.Id)
+
+	_, err = server.SubmitResult(context.Background(), &pb.TaskResult{Id: requeued.Id, AgentId: alive.AgentId, Res: 6})
+	assert.NoError(t, err)
+
+	r := <-done
+	assert.NoError(t, r.err)
+	assert.Equal(t, 6.0, r.res)
+
+	// поздний результат потерянного агента игнорируется
+	_, err = server.SubmitResult(context.Background(), &pb.TaskResult{Id: task.Id, AgentId: lost.AgentId, Res: 6})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// незарегистрированный агент задач не получает, задача остается в очереди
+	o.queue.push(&models.Task{ID: "next", Operation: "+"})
+	_, err = server.GetTask(context.Background(), &pb.GetTaskRequest{AgentId: "unknown"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, 1, o.queue.len())
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+)
+
+// политика повтора вызовов агента (см. Config.Retry*)
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	codes       []codes.Code
+}
+
+func (o *Orkestrator) retryPolicy() retryPolicy {
+	return retryPolicy{
+		maxAttempts: max(o.Config.RetryMaxAttempts, 1),
+		baseDelay:   time.Duration(o.Config.RetryBaseDelay) * time.Millisecond,
+		maxDelay:    time.Duration(o.Config.RetryMaxDelay) * time.Millisecond,
+		codes:       o.Config.RetryCodes,
+	}
+}
+
+// ошибку можно повторить, если это ошибка вызова агента с кодом из списка.
+// ошибки вычисления (деление на ноль и т.п.) не повторяются никогда
+func (p retryPolicy) retryable(err error) bool {
+	var code codes.Code
+	switch {
+	case errors.Is(err, models.ErrOperationTimeout):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, models.ErrNoAgents):
+		// в пуле нет доступных агентов: код последней ошибки агента, если она была
+		code = codes.Unavailable
+		if s, ok := status.FromError(err); ok {
+			code = s.Code()
+		}
+	default:
+		s, ok := status.FromError(err)
+		if !ok {
+			return false
+		}
+		code = s.Code()
+	}
+	return slices.Contains(p.codes, code)
+}
+
+// пауза перед повтором номер attempt (1, 2, ...): baseDelay * 2^(attempt-1), но не больше
+// maxDelay, со случайным разбросом в пределах половины паузы, чтобы повторы разных
+// операций не совпадали по времени
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	d := p.baseDelay
+	for i := 1; i < attempt && d < p.maxDelay; i++ {
+		d *= 2
+	}
+	if p.maxDelay > 0 {
+		d = min(d, p.maxDelay)
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// вычисление операции с повторами по политике retryPolicy. attempts - счетчик
+// попыток выражения (может быть nil), key - ключ операции (см. stepKey)
+func (o *Orkestrator) calculateWithRetry(ctx context.Context, attempts *atomic.Int32, key string, mode string, op string, x, y numeric.Value) (numeric.Value, error) {
+	p := o.retryPolicy()
+
+	for attempt := 1; ; attempt++ {
+		if attempts != nil {
+			attempts.Add(1)
+		}
+		res, err := o.calculate(ctx, key, mode, op, x, y)
+		if err == nil || attempt >= p.maxAttempts || !p.retryable(err) || ctx.Err() != nil {
+			return res, err
+		}
+
+		delay := p.backoff(attempt)
+		o.log.Warn(fmt.Sprintf("operation %s failed (attempt %d), retrying in %s: %s", op, attempt, delay, err))
+		select {
+		case <-ctx.Done():
+			return numeric.Value{}, err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// тесты для выбора ошибок, которые можно повторить
+func TestRetryPolicy_Retryable(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	tests := []struct {
+		name     string
+		codes    []codes.Code
+		err      error
+		expected bool
+	}{
+		{name: "Unavailable", err: unavailable, expected: true},
+		{name: "Resource exhausted", err: status.Error(codes.ResourceExhausted, models.ErrAgentBusy.Error()), expected: true},
+		{name: "No agents after unavailable", err: fmt.Errorf("%w: %w", models.ErrNoAgents, unavailable), expected: true},
+		{name: "No agents in pool", err: models.ErrNoAgents, expected: true},
+		{name: "Division by zero", err: models.ErrDivisionByZero, expected: false},
+		{name: "Unknown code", err: status.Error(codes.Unknown, "boom"), expected: false},
+		{name: "Mode not supported", err: models.ErrModeNotSupported, expected: false},
+		{name: "Cancelled", err: status.Error(codes.Canceled, "context canceled"), expected: false},
+		{name: "Operation timeout", err: models.ErrOperationTimeout, expected: false},
+		{name: "Operation timeout in list", codes: []codes.Code{codes.DeadlineExceeded}, err: models.ErrOperationTimeout, expected: true},
+		{name: "Code not in list", codes: []codes.Code{codes.DeadlineExceeded}, err: unavailable, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := retryPolicy{codes: tt.codes}
+			if p.codes == nil {
+				p.codes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+			}
+			assert.Equal(t, tt.expected, p.retryable(tt.err))
+		})
+	}
+}
+
+// пауза растет вдвое, не превышает maxDelay и случайна в пределах половины
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: time.Second}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{attempt: 10, min: 500 * time.Millisecond, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 100 {
+			d := p.backoff(tt.attempt)
+			assert.GreaterOrEqual(t, d, tt.min)
+			assert.LessOrEqual(t, d, tt.max)
+		}
+	}
+	assert.Zero(t, retryPolicy{}.backoff(3))
+}
+
+// клиент, первые failures вызовов которого завершаются ошибкой err
+type flakyClient struct {
+	pb.CalcServiceClient
+	err      error
+	failures int32
+	calls    atomic.Int32
+}
+
+func (c *flakyClient) Calculation(ctx context.Context, in *pb.TaskRequest, opts ...grpc.CallOption) (*pb.ResResponse, error) {
+	if c.calls.Add(1) <= c.failures {
+		return nil, c.err
+	}
+	return pb.NewResResponse(in.GetArg1Double() + in.GetArg2Double()), nil
+}
+
+// ошибки соединения повторяются с паузой, ошибки вычисления - нет.
+// число попыток сохраняется в выражении
+func TestOrkestrator_Retry(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	tests := []struct {
+		name             string
+		client           *flakyClient
+		expectedRes      float64
+		expectedErr      error
+		expectedStatus   string
+		expectedAttempts int
+	}{
+		{
+			name:             "Transient failure",
+			client:           &flakyClient{err: unavailable, failures: 2},
+			expectedRes:      3,
+			expectedStatus:   models.StatusCompleted,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "Attempts exhausted",
+			client:           &flakyClient{err: unavailable, failures: 10},
+			expectedErr:      models.ErrNoAgents,
+			expectedStatus:   models.StatusFailed,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "Division by zero is not retried",
+			client:           &flakyClient{err: status.Error(codes.Unknown, models.ErrDivisionByZero.Error()), failures: 10},
+			expectedErr:      models.ErrDivisionByZero,
+			expectedStatus:   models.StatusFailed,
+			expectedAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupTestDB(t)
+			defer db.Close()
+
+			agents := newAgentPool(100, time.Minute, zap.NewNop())
+			agents.add("flaky", tt.client)
+			o := &Orkestrator{
+				Config: &config.Config{
+					RetryMaxAttempts: 3,
+					RetryBaseDelay:   1,
+					RetryMaxDelay:    5,
+					RetryCodes:       []codes.Code{codes.Unavailable},
+				},
+				log:    zap.NewNop(),
+				exprs:  db,
+				ctx:    context.Background(),
+				agents: agents,
+			}
+
+			res, err := o.ExpressionOperations(models.CalcRequest{Expression: "1+2"}, "testuser")
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedRes, res.Result)
+				// синхронный ответ содержит число попыток, как и сохраненное выражение
+				assert.Equal(t, tt.expectedAttempts, res.Attempts)
+			}
+			assert.Equal(t, int32(tt.expectedAttempts), tt.client.calls.Load())
+
+			exprs, err := o.GetAllExpressions("testuser")
+			assert.NoError(t, err)
+			for _, e := range exprs {
+				assert.Equal(t, tt.expectedStatus, e.Status)
+				assert.Equal(t, tt.expectedAttempts, e.Attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// выражение, готовое к вычислению
+type job struct {
+	id       string
+	user     string
+	plan     *plan
+	bindings map[string]float64
+	// режим вычисления (см. models.Mode*)
+	mode string
+	// не брать результаты операций из кэша (см. models.CalcRequest)
+	noCache bool
+	// результаты шагов плана, вычисленных до перезапуска оркестратора (см. Orkestrator.Recover)
+	results map[int]stepResult
+	// вычисленные шаги выражения (заполняются в Orkestrator.evaluate)
+	steps []models.Step
+
+	// отмена вычисления (см. Orkestrator.CancelExpression) и срок вычисления
+	// всего выражения. done закрывается, когда результат, ошибка или отмена записаны в БД
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// число попыток вычисления операций, включая повторные
+	attempts atomic.Int32
+}
+
+// timeout - время на вычисление всего выражения, 0 - без ограничения
+func newJob(id string, user string, timeout time.Duration) *job {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	return &job{id: id, user: user, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// планировщик фоновых вычислений. хранит выполняющиеся выражения,
+// нулевое значение готово к использованию
+type scheduler struct {
+	mu      sync.Mutex
+	running map[string]*job
+}
+
+// запуск вычисления выражения в фоне
+func (s *scheduler) start(j *job, run func(*job)) {
+	s.add(j)
+	go func() {
+		defer s.finish(j)
+		run(j)
+	}()
+}
+
+// вычисление выражения в текущей горутине. пока оно идет, его можно отменить
+func (s *scheduler) do(j *job, run func(*job)) {
+	s.add(j)
+	defer s.finish(j)
+	run(j)
+}
+
+func (s *scheduler) add(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running == nil {
+		s.running = make(map[string]*job)
+	}
+	s.running[j.id] = j
+}
+
+func (s *scheduler) finish(j *job) {
+	s.mu.Lock()
+	delete(s.running, j.id)
+	s.mu.Unlock()
+
+	j.cancel()
+	close(j.done)
+}
+
+// выполняющееся выражение по ID
+func (s *scheduler) get(id string) (*job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.running[id]
+	return j, ok
+}
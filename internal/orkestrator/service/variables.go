@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/expr"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/ArtemiySps/calc_go_final/pkg/numeric"
+)
+
+const variablesTable = `
+CREATE TABLE IF NOT EXISTS variables(
+	user TEXT NOT NULL,
+	name TEXT NOT NULL,
+	value REAL NOT NULL,
+	PRIMARY KEY (user, name)
+);`
+
+// создание таблицы сохраненных переменных пользователей
+func createVariablesTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, variablesTable)
+	return err
+}
+
+// сохранить переменную пользователя (существующая перезаписывается)
+func (o *Orkestrator) SetVariable(user string, name string, value float64) error {
+	if !expr.IsIdentifier(name) {
+		return models.ErrBadVariableName
+	}
+
+	var q = `
+	INSERT INTO variables (user, name, value) values ($1, $2, $3)
+	ON CONFLICT (user, name) DO UPDATE SET value = excluded.value
+	`
+	_, err := o.exprs.ExecContext(o.ctx, q, user, name, value)
+	return err
+}
+
+// проверка имен переменных из запроса. значения ссылок @<id> в переменных не передаются:
+// они берутся только из результатов выражений (см. referencedValue)
+func checkVariableNames(vars map[string]float64) error {
+	for name := range vars {
+		if !expr.IsIdentifier(name) {
+			return fmt.Errorf("%w: %q", models.ErrBadVariableName, name)
+		}
+	}
+	return nil
+}
+
+// получить все сохраненные переменные пользователя
+func (o *Orkestrator) GetVariables(user string) (map[string]float64, error) {
+	vars := make(map[string]float64)
+	var q = "SELECT name, value FROM variables WHERE user = $1"
+
+	rows, err := o.exprs.QueryContext(o.ctx, q, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var value float64
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+	return vars, rows.Err()
+}
+
+// удалить сохраненную переменную пользователя
+func (o *Orkestrator) DeleteVariable(user string, name string) error {
+	q := `DELETE FROM variables WHERE user = $1 AND name = $2`
+
+	result, err := o.exprs.ExecContext(o.ctx, q, user, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrVariableNotFound
+	}
+	return nil
+}
+
+// результат выражения пользователя для ссылки @<id> в режиме mode. чужие, невычисленные,
+// отмененные, не уложившиеся в срок и завершившиеся ошибкой выражения использовать нельзя.
+// точная запись результата переносится без потерь, если она есть в режиме mode
+// (0.1 из decimal в rational, 2+3i в complex), иначе берется приближение результата.
+// комплексный результат с мнимой частью в других режимах - ошибка
+func (o *Orkestrator) referencedValue(id string, user string, mode string) (numeric.Value, error) {
+	e, err := o.GetExpression(id, user)
+	if err != nil {
+		return numeric.Value{}, models.ErrReferenceNotFound
+	}
+
+	switch e.Status {
+	case models.StatusCompleted:
+	case models.StatusFailed:
+		return numeric.Value{}, models.ErrReferenceFailed
+	case models.StatusCancelled:
+		return numeric.Value{}, models.ErrReferenceCancelled
+	case models.StatusTimeout:
+		return numeric.Value{}, models.ErrReferenceTimeout
+	default:
+		return numeric.Value{}, models.ErrReferencePending
+	}
+
+	if e.Value != "" {
+		if v, err := numeric.Parse(mode, e.Value); err == nil {
+			return v, nil
+		}
+		// мнимую часть нельзя перенести в действительный режим
+		if e.Complex != nil && e.Complex.Imag != 0 {
+			return numeric.Value{}, models.ErrImaginaryNumber
+		}
+		// дробь в режиме decimal округляется, как результат деления
+		if mode == models.ModeDecimal && e.Decimal != "" {
+			return numeric.Parse(mode, e.Decimal)
+		}
+	}
+	return numeric.FromFloat(mode, e.Result), nil
+}
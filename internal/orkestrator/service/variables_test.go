@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/internal/orkestrator/config"
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	pb "github.com/ArtemiySps/calc_go_final/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// ID выражений для ссылок в тестах
+const (
+	refDone  = "00000000-0000-4000-8000-000000000001"
+	refWait  = "00000000-0000-4000-8000-000000000002"
+	refFail  = "00000000-0000-4000-8000-000000000003"
+	refAlien = "00000000-0000-4000-8000-000000000004"
+	refNope  = "00000000-0000-4000-8000-000000000005"
+	refThird = "00000000-0000-4000-8000-000000000006"
+	refTenth = "00000000-0000-4000-8000-000000000007"
+	refHalf  = "00000000-0000-4000-8000-000000000008"
+	refRoot  = "00000000-0000-4000-8000-000000000009"
+	refReal  = "00000000-0000-4000-8000-000000000010"
+)
+
+func TestVariables_Integration(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	o := &Orkestrator{
+		exprs: db,
+		ctx:   context.Background(),
+	}
+
+	assert.NoError(t, o.SetVariable("testuser", "rate", 1.5))
+	assert.NoError(t, o.SetVariable("testuser", "rate", 2))
+	assert.NoError(t, o.SetVariable("otheruser", "rate", 10))
+	assert.ErrorIs(t, o.SetVariable("testuser", "1x", 1), models.ErrBadVariableName)
+
+	vars, err := o.GetVariables("testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"rate": 2}, vars)
+
+	assert.NoError(t, o.DeleteVariable("testuser", "rate"))
+	assert.ErrorIs(t, o.DeleteVariable("testuser", "rate"), models.ErrVariableNotFound)
+
+	vars, err = o.GetVariables("testuser")
+	assert.NoError(t, err)
+	assert.Empty(t, vars)
+}
+
+// сохраненные переменные и ссылки на результаты подставляются в выражение
+func TestOrkestrator_ExpressionOperations_References(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO expressions (user, id, expr, status, result, error) VALUES
+		('testuser', ?, '2+2', 'completed', 4, ''),
+		('testuser', ?, '2+2', 'pending', 0, ''),
+		('testuser', ?, '2/0', 'failed', 0, 'division by zero'),
+		('otheruser', ?, '1+1', 'completed', 2, '')
+	`, refDone, refWait, refFail, refAlien)
+	assert.NoError(t, err)
+
+	mockClient := new(MockCalcClient)
+	mockClient.On("Calculation", mock.Anything, mock.MatchedBy(func(in *pb.TaskRequest) bool {
+		return in.Opr == "*" && in.Arg1 == 4 && in.Arg2 == 3
+	})).Return(&pb.ResResponse{Res: 12}, nil).Once()
+
+	o := &Orkestrator{
+		Config: &config.Config{},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+		agents: singleAgent(mockClient),
+	}
+	assert.NoError(t, o.SetVariable("testuser", "k", 3))
+
+	res, err := o.ExpressionOperations(models.CalcRequest{Expression: "@" + refDone + "*k"}, "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, 12.0, res.Result)
+
+	exprs, err := o.GetAllExpressions("testuser")
+	assert.NoError(t, err)
+	for _, e := range exprs {
+		if e.Expr == "@"+refDone+"*k" {
+			assert.Equal(t, map[string]float64{"@" + refDone: 4, "k": 3}, e.Variables)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		expr        string
+		variables   map[string]float64
+		expectedErr error
+	}{
+		{name: "Pending expression", expr: "@" + refWait + "*2", expectedErr: models.ErrReferencePending},
+		{name: "Failed expression", expr: "@" + refFail + "*2", expectedErr: models.ErrReferenceFailed},
+		{name: "Other user's expression", expr: "@" + refAlien + "*2", expectedErr: models.ErrReferenceNotFound},
+		{name: "Unknown expression", expr: "@" + refNope + "*2", expectedErr: models.ErrReferenceNotFound},
+		// значение ссылки нельзя передать в переменных запроса
+		{name: "Pending expression via variables", expr: "@" + refWait + "*2", variables: map[string]float64{"@" + refWait: 5}, expectedErr: models.ErrBadVariableName},
+		{name: "Other user's expression via variables", expr: "@" + refAlien + "*2", variables: map[string]float64{"@" + refAlien: 5}, expectedErr: models.ErrBadVariableName},
+		{name: "Bad variable name", expr: "k*2", variables: map[string]float64{"1 bad": 5}, expectedErr: models.ErrBadVariableName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := o.ExpressionOperations(models.CalcRequest{Expression: tt.expr, Variables: tt.variables}, "testuser")
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+
+	mockClient.AssertExpectations(t)
+}
+
+// ссылка на результат в точном режиме переносит точную запись, а не приближение float64.
+// комплексный результат с мнимой частью можно использовать только в режиме complex
+func TestOrkestrator_ExactReferences(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO expressions (user, id, expr, status, result, error, mode, value) VALUES
+		('testuser', ?, '1/3', 'completed', 0.3333333333333333, '', 'rational', '1/3'),
+		('testuser', ?, '1/10', 'completed', 0.1, '', 'decimal', '0.1'),
+		('testuser', ?, '1/2', 'completed', 0.5, '', 'float', ''),
+		('testuser', ?, 'sqrt(-4)+2', 'completed', 2, '', 'complex', '2+2i'),
+		('testuser', ?, '2*i*i', 'completed', -2, '', 'complex', '-2')
+	`, refThird, refTenth, refHalf, refRoot, refReal)
+	assert.NoError(t, err)
+
+	o := &Orkestrator{
+		Config: &config.Config{LocalCostThreshold: 10000, DecimalScale: 5},
+		log:    zap.NewNop(),
+		exprs:  db,
+		ctx:    context.Background(),
+	}
+
+	tests := []struct {
+		name        string
+		req         models.CalcRequest
+		expectedVal string
+		expectedErr error
+	}{
+		{name: "Rational", req: models.CalcRequest{Expression: "@" + refThird + "*3", Mode: models.ModeRational}, expectedVal: "1"},
+		{name: "Decimal in rational mode", req: models.CalcRequest{Expression: "@" + refTenth + "*3", Mode: models.ModeRational}, expectedVal: "3/10"},
+		{name: "Decimal", req: models.CalcRequest{Expression: "@" + refTenth + "*3", Mode: models.ModeDecimal}, expectedVal: "0.3"},
+		{name: "Rational in decimal mode", req: models.CalcRequest{Expression: "@" + refThird + "*3", Mode: models.ModeDecimal}, expectedVal: "0.99999"},
+		{name: "Float in rational mode", req: models.CalcRequest{Expression: "@" + refHalf + "*3", Mode: models.ModeRational}, expectedVal: "3/2"},
+		{name: "Complex", req: models.CalcRequest{Expression: "@" + refRoot + "*i", Mode: models.ModeComplex}, expectedVal: "-2+2i"},
+		{name: "Real complex in decimal mode", req: models.CalcRequest{Expression: "@" + refReal + "*3", Mode: models.ModeDecimal}, expectedVal: "-6"},
+		{name: "Complex in float mode", req: models.CalcRequest{Expression: "@" + refRoot + "*3"}, expectedErr: models.ErrImaginaryNumber},
+		{name: "Complex in rational mode", req: models.CalcRequest{Expression: "@" + refRoot + "*3", Mode: models.ModeRational}, expectedErr: models.ErrImaginaryNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := o.ExpressionOperations(tt.req, "testuser")
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedVal, e.Value)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// узел синтаксического дерева выражения
+type Node interface {
+	// смещение начала узла в исходном выражении
+	Pos() int
+	// смещение сразу после конца узла
+	End() int
+}
+
+// числовой литерал. у мнимого числа (3i, i) Value - коэффициент при мнимой единице
+type Number struct {
+	Value float64
+	Text  string
+	Imag  bool
+	From  int
+}
+
+// идентификатор: встроенная константа или переменная
+type Ident struct {
+	Name string
+	From int
+}
+
+// ссылка на результат ранее вычисленного выражения: @<id>
+type Ref struct {
+	ID   string
+	From int
+}
+
+// ключ ссылки в значениях идентификаторов (см. Bind)
+func (n *Ref) Key() string {
+	return "@" + n.ID
+}
+
+// унарная операция: -x или +x
+type Unary struct {
+	Op   string
+	X    Node
+	From int
+}
+
+// бинарная операция: x op y
+type Binary struct {
+	Op   string
+	X, Y Node
+}
+
+// вызов встроенной функции: name(args...)
+type Call struct {
+	Name     string
+	Args     []Node
+	From, To int
+}
+
+// выражение в скобках. сохраняется, чтобы позиции узлов совпадали с исходником
+type Paren struct {
+	X        Node
+	From, To int
+}
+
+func (n *Number) Pos() int { return n.From }
+func (n *Number) End() int { return n.From + len(n.Text) }
+
+func (n *Ident) Pos() int { return n.From }
+func (n *Ident) End() int { return n.From + len(n.Name) }
+
+func (n *Ref) Pos() int { return n.From }
+func (n *Ref) End() int { return n.From + len(n.Key()) }
+
+func (n *Unary) Pos() int { return n.From }
+func (n *Unary) End() int { return n.X.End() }
+
+func (n *Binary) Pos() int { return n.X.Pos() }
+func (n *Binary) End() int { return n.Y.End() }
+
+func (n *Call) Pos() int { return n.From }
+func (n *Call) End() int { return n.To }
+
+func (n *Paren) Pos() int { return n.From }
+func (n *Paren) End() int { return n.To }
+
+// функция для получения нормализованной записи дерева: каждая операция в скобках,
+// лишние скобки убраны. используется в тестах и для сравнения выражений
+func String(n Node) string {
+	var sb strings.Builder
+	writeNode(&sb, n)
+	return sb.String()
+}
+
+func writeNode(sb *strings.Builder, n Node) {
+	switch n := n.(type) {
+	case *Number:
+		sb.WriteString(n.Text)
+	case *Ident:
+		sb.WriteString(n.Name)
+	case *Ref:
+		sb.WriteString(n.Key())
+	case *Unary:
+		sb.WriteString("(")
+		sb.WriteString(n.Op)
+		writeNode(sb, n.X)
+		sb.WriteString(")")
+	case *Binary:
+		sb.WriteString("(")
+		writeNode(sb, n.X)
+		sb.WriteString(" " + n.Op + " ")
+		writeNode(sb, n.Y)
+		sb.WriteString(")")
+	case *Call:
+		sb.WriteString(n.Name + "(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeNode(sb, arg)
+		}
+		sb.WriteString(")")
+	case *Paren:
+		writeNode(sb, n.X)
+	}
+}
+
+// функция для снятия скобок с узла
+func Unparen(n Node) Node {
+	for {
+		p, ok := n.(*Paren)
+		if !ok {
+			return n
+		}
+		n = p.X
+	}
+}
+
+// функция для обхода дерева в глубину: f вызывается для узла, затем для его потомков слева направо
+func Inspect(n Node, f func(Node)) {
+	f(n)
+	switch n := n.(type) {
+	case *Unary:
+		Inspect(n.X, f)
+	case *Binary:
+		Inspect(n.X, f)
+		Inspect(n.Y, f)
+	case *Call:
+		for _, arg := range n.Args {
+			Inspect(arg, f)
+		}
+	case *Paren:
+		Inspect(n.X, f)
+	}
+}
+
+// функция для проверки, что в выражении нет мнимых чисел (они есть только в режиме complex).
+// возвращает ошибку ErrImaginaryNumber с позицией первого мнимого числа
+func RequireReal(src string, node Node) error {
+	var err error
+	Inspect(node, func(n Node) {
+		if num, ok := n.(*Number); ok && num.Imag && err == nil {
+			err = newParseError(src, num.Pos(), num.End(), fmt.Sprintf("%q", num.Text), nil, models.ErrImaginaryNumber)
+		}
+	})
+	return err
+}
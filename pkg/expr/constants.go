@@ -0,0 +1,63 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// встроенные константы
+var Constants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+	"phi": math.Phi,
+}
+
+// функция для связывания идентификаторов и ссылок выражения со значениями. переменные из vars
+// перекрывают встроенные константы. значения ссылок @<id> запрашиваются у results; при
+// results == nil (повторный разбор сохраненного выражения) они берутся из сохраненных
+// значений vars по ключу "@<id>", а отсутствующие ссылки не проверяются.
+// возвращает значения всех использованных идентификаторов и ссылок, для первого
+// неизвестного - ошибку (ErrUndefinedVariable или ошибку results) с его позицией
+func Bind(src string, node Node, vars map[string]float64, results func(id string) (float64, error)) (map[string]float64, error) {
+	bindings := make(map[string]float64)
+	var err error
+
+	Inspect(node, func(n Node) {
+		if err != nil {
+			return
+		}
+
+		switch n := n.(type) {
+		case *Ident:
+			if value, ok := vars[n.Name]; ok {
+				bindings[n.Name] = value
+			} else if value, ok := Constants[n.Name]; ok {
+				bindings[n.Name] = value
+			} else {
+				err = newParseError(src, n.Pos(), n.End(), fmt.Sprintf("%q", n.Name), nil, models.ErrUndefinedVariable)
+			}
+
+		case *Ref:
+			if results == nil {
+				if value, ok := vars[n.Key()]; ok {
+					bindings[n.Key()] = value
+				}
+				return
+			}
+			value, resErr := results(n.ID)
+			if resErr != nil {
+				err = newParseError(src, n.Pos(), n.End(), fmt.Sprintf("%q", n.Key()), nil, resErr)
+				return
+			}
+			bindings[n.Key()] = value
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return bindings, nil
+}
@@ -0,0 +1,73 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ошибка разбора выражения с позицией. Offset и Length считаются в символах,
+// Err - одна из ошибок models (ErrBadExpression, ErrUnexpectedSymbol, ErrBadNumber),
+// поэтому errors.Is продолжает работать
+type ParseError struct {
+	Offset   int
+	Length   int
+	Token    string
+	Expected []string
+	Err      error
+}
+
+// функция для создания ошибки по байтовым позициям [from, to) в src
+func newParseError(src string, from, to int, token string, expected []string, err error) *ParseError {
+	return &ParseError{
+		Offset:   utf8.RuneCountInString(src[:from]),
+		Length:   utf8.RuneCountInString(src[from:to]),
+		Token:    token,
+		Expected: expected,
+		Err:      err,
+	}
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s at position %d: unexpected %s", e.Err, e.Offset, e.Token)
+	if len(e.Expected) > 0 {
+		msg += ", expected " + strings.Join(e.Expected, ", ")
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error    string   `json:"error"`
+		Message  string   `json:"message"`
+		Offset   int      `json:"offset"`
+		Length   int      `json:"length"`
+		Token    string   `json:"token"`
+		Expected []string `json:"expected,omitempty"`
+	}{
+		Error:    e.Err.Error(),
+		Message:  e.Error(),
+		Offset:   e.Offset,
+		Length:   e.Length,
+		Token:    e.Token,
+		Expected: e.Expected,
+	})
+}
+
+// функция для вывода выражения с подчеркнутым местом ошибки:
+//
+//	1+2+a
+//	    ^
+func (e *ParseError) Snippet(src string) string {
+	var sb strings.Builder
+	sb.WriteString(src)
+	sb.WriteString("\n")
+	sb.WriteString(strings.Repeat(" ", e.Offset))
+	sb.WriteString(strings.Repeat("^", max(e.Length, 1)))
+	return sb.String()
+}
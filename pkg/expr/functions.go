@@ -0,0 +1,53 @@
+package expr
+
+import (
+	"fmt"
+)
+
+// встроенная функция. MaxArgs < 0 - функция с переменным числом аргументов
+type Function struct {
+	MinArgs int
+	MaxArgs int
+}
+
+// встроенные функции. функции с переменным числом аргументов вычисляются попарно:
+// max(a, b, c) = max(max(a, b), c)
+var Functions = map[string]Function{
+	"sqrt":  {MinArgs: 1, MaxArgs: 1},
+	"sin":   {MinArgs: 1, MaxArgs: 1},
+	"cos":   {MinArgs: 1, MaxArgs: 1},
+	"log":   {MinArgs: 1, MaxArgs: 1},
+	"abs":   {MinArgs: 1, MaxArgs: 1},
+	"round": {MinArgs: 1, MaxArgs: 1},
+	"min":   {MinArgs: 1, MaxArgs: -1},
+	"max":   {MinArgs: 1, MaxArgs: -1},
+}
+
+// функция с переменным числом аргументов
+func (f Function) Variadic() bool {
+	return f.MaxArgs < 0
+}
+
+// проверка числа аргументов
+func (f Function) accepts(n int) bool {
+	return n >= f.MinArgs && (f.Variadic() || n <= f.MaxArgs)
+}
+
+// описание допустимого числа аргументов для сообщений об ошибках
+func (f Function) describeArity() string {
+	switch {
+	case f.Variadic():
+		return fmt.Sprintf("at least %s", pluralArgs(f.MinArgs))
+	case f.MinArgs == f.MaxArgs:
+		return pluralArgs(f.MinArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", f.MinArgs, f.MaxArgs)
+	}
+}
+
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
@@ -0,0 +1,186 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// мнимая единица. записывается отдельно (i) или после числа (3i, 2.5i)
+const imagUnit = "i"
+
+// функция для проверки, является ли строка идентификатором (подходит для имени переменной).
+// имя i занято мнимой единицей
+func IsIdentifier(name string) bool {
+	if name == "" || name == imagUnit || !isLetter(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isLetter(name[i]) && !isDigit(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// символ идентификатора после первого: буквы и цифры
+func isIdentPart(c byte) bool {
+	return isLetter(c) || isDigit(c)
+}
+
+// длины групп ID выражения (uuid вида 8-4-4-4-12)
+var refGroups = []int{8, 4, 4, 4, 12}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// длина ID выражения в начале строки или 0, если там нет uuid. ссылка занимает ровно
+// uuid, поэтому в @<id>-1 минус остается вычитанием
+func refLen(s string) int {
+	n := 0
+	for i, group := range refGroups {
+		if i > 0 {
+			if n >= len(s) || s[n] != '-' {
+				return 0
+			}
+			n++
+		}
+		for j := 0; j < group; j++ {
+			if n >= len(s) || !isHexDigit(s[n]) {
+				return 0
+			}
+			n++
+		}
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// лексемы из одного символа
+var singleTokens = map[byte]TokenKind{
+	'+': TokenPlus,
+	'-': TokenMinus,
+	'*': TokenStar,
+	'/': TokenSlash,
+	'%': TokenPercent,
+	'^': TokenCaret,
+	'(': TokenLParen,
+	')': TokenRParen,
+	',': TokenComma,
+}
+
+// функция для разбиения выражения на лексемы. последней всегда идет TokenEOF
+func Tokenize(src string) ([]Token, error) {
+	var tokens []Token
+
+	for pos := 0; pos < len(src); {
+		c := src[pos]
+
+		switch {
+		case isSpace(c):
+			pos++
+
+		case isDigit(c) || c == '.':
+			end := scanNumber(src, pos)
+			text := src[pos:end]
+			if _, err := parseNumber(text); err != nil {
+				return nil, newParseError(src, pos, end, fmt.Sprintf("%q", text), nil, err)
+			}
+			// мнимое число: i сразу после числа, если за ней не продолжается идентификатор
+			if src[end:] == imagUnit || strings.HasPrefix(src[end:], imagUnit) && !isIdentPart(src[end+1]) {
+				end++
+				text = src[pos:end]
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: text, Pos: pos})
+			pos = end
+
+		case isLetter(c):
+			end := pos + 1
+			for end < len(src) && isIdentPart(src[end]) {
+				end++
+			}
+			kind := TokenIdent
+			if src[pos:end] == imagUnit {
+				kind = TokenNumber
+			}
+			tokens = append(tokens, Token{Kind: kind, Text: src[pos:end], Pos: pos})
+			pos = end
+
+		case c == '@' && refLen(src[pos+1:]) > 0:
+			end := pos + 1 + refLen(src[pos+1:])
+			tokens = append(tokens, Token{Kind: TokenRef, Text: src[pos:end], Pos: pos})
+			pos = end
+
+		case c == '/' && pos+1 < len(src) && src[pos+1] == '/':
+			tokens = append(tokens, Token{Kind: TokenDoubleSlash, Text: src[pos : pos+2], Pos: pos})
+			pos += 2
+
+		default:
+			kind, ok := singleTokens[c]
+			if !ok {
+				_, size := utf8.DecodeRuneInString(src[pos:])
+				text := src[pos : pos+size]
+				return nil, newParseError(src, pos, pos+size, fmt.Sprintf("%q", text), nil, models.ErrUnexpectedSymbol)
+			}
+			tokens = append(tokens, Token{Kind: kind, Text: src[pos : pos+1], Pos: pos})
+			pos++
+		}
+	}
+
+	tokens = append(tokens, Token{Kind: TokenEOF, Pos: len(src)})
+	return tokens, nil
+}
+
+// функция для перевода текста числа в float64. у мнимого числа (3i, i)
+// возвращается коэффициент при мнимой единице
+func parseNumber(text string) (float64, error) {
+	if coef, ok := strings.CutSuffix(text, imagUnit); ok {
+		if coef == "" {
+			return 1, nil
+		}
+		text = coef
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, models.ErrBadNumber
+	}
+	return value, nil
+}
+
+// возвращает позицию конца числа, начинающегося в pos: цифры и точки,
+// затем необязательная экспонента вида e10, E-9, e+3
+func scanNumber(src string, pos int) int {
+	end := pos
+	for end < len(src) && (isDigit(src[end]) || src[end] == '.') {
+		end++
+	}
+
+	if end < len(src) && (src[end] == 'e' || src[end] == 'E') {
+		exp := end + 1
+		if exp < len(src) && (src[exp] == '+' || src[exp] == '-') {
+			exp++
+		}
+		if exp < len(src) && isDigit(src[exp]) {
+			end = exp
+			for end < len(src) && isDigit(src[end]) {
+				end++
+			}
+		}
+	}
+	return end
+}
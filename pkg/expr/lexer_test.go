@@ -0,0 +1,165 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// тесты для Tokenize
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name           string
+		expr           string
+		expectedTokens []Token
+		expectedErr    error
+	}{
+		{
+			name: "Operators and numbers",
+			expr: "1 + 2.5*(3)",
+			expectedTokens: []Token{
+				{Kind: TokenNumber, Text: "1", Pos: 0},
+				{Kind: TokenPlus, Text: "+", Pos: 2},
+				{Kind: TokenNumber, Text: "2.5", Pos: 4},
+				{Kind: TokenStar, Text: "*", Pos: 7},
+				{Kind: TokenLParen, Text: "(", Pos: 8},
+				{Kind: TokenNumber, Text: "3", Pos: 9},
+				{Kind: TokenRParen, Text: ")", Pos: 10},
+				{Kind: TokenEOF, Pos: 11},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Scientific notation",
+			expr: "6.02e23/1E-9-.5",
+			expectedTokens: []Token{
+				{Kind: TokenNumber, Text: "6.02e23", Pos: 0},
+				{Kind: TokenSlash, Text: "/", Pos: 7},
+				{Kind: TokenNumber, Text: "1E-9", Pos: 8},
+				{Kind: TokenMinus, Text: "-", Pos: 12},
+				{Kind: TokenNumber, Text: ".5", Pos: 13},
+				{Kind: TokenEOF, Pos: 15},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Imaginary numbers",
+			expr: "(2+3i)*(1-i)+pi",
+			expectedTokens: []Token{
+				{Kind: TokenLParen, Text: "(", Pos: 0},
+				{Kind: TokenNumber, Text: "2", Pos: 1},
+				{Kind: TokenPlus, Text: "+", Pos: 2},
+				{Kind: TokenNumber, Text: "3i", Pos: 3},
+				{Kind: TokenRParen, Text: ")", Pos: 5},
+				{Kind: TokenStar, Text: "*", Pos: 6},
+				{Kind: TokenLParen, Text: "(", Pos: 7},
+				{Kind: TokenNumber, Text: "1", Pos: 8},
+				{Kind: TokenMinus, Text: "-", Pos: 9},
+				{Kind: TokenNumber, Text: "i", Pos: 10},
+				{Kind: TokenRParen, Text: ")", Pos: 11},
+				{Kind: TokenPlus, Text: "+", Pos: 12},
+				{Kind: TokenIdent, Text: "pi", Pos: 13},
+				{Kind: TokenEOF, Pos: 15},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Power, modulo and integer division",
+			expr: "2^3%4//5/6",
+			expectedTokens: []Token{
+				{Kind: TokenNumber, Text: "2", Pos: 0},
+				{Kind: TokenCaret, Text: "^", Pos: 1},
+				{Kind: TokenNumber, Text: "3", Pos: 2},
+				{Kind: TokenPercent, Text: "%", Pos: 3},
+				{Kind: TokenNumber, Text: "4", Pos: 4},
+				{Kind: TokenDoubleSlash, Text: "//", Pos: 5},
+				{Kind: TokenNumber, Text: "5", Pos: 7},
+				{Kind: TokenSlash, Text: "/", Pos: 8},
+				{Kind: TokenNumber, Text: "6", Pos: 9},
+				{Kind: TokenEOF, Pos: 10},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:           "Two dots",
+			expr:           "1.2.3+1",
+			expectedTokens: nil,
+			expectedErr:    models.ErrBadNumber,
+		},
+		{
+			name:           "Lone dot",
+			expr:           "1+.",
+			expectedTokens: nil,
+			expectedErr:    models.ErrBadNumber,
+		},
+		{
+			name: "Function call",
+			expr: "max(x1, 2)",
+			expectedTokens: []Token{
+				{Kind: TokenIdent, Text: "max", Pos: 0},
+				{Kind: TokenLParen, Text: "(", Pos: 3},
+				{Kind: TokenIdent, Text: "x1", Pos: 4},
+				{Kind: TokenComma, Text: ",", Pos: 6},
+				{Kind: TokenNumber, Text: "2", Pos: 8},
+				{Kind: TokenRParen, Text: ")", Pos: 9},
+				{Kind: TokenEOF, Pos: 10},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Reference",
+			expr: "@3f2a9c1e-0b4d-4e2a-9f1c-7d8e6a5b4c3d*2",
+			expectedTokens: []Token{
+				{Kind: TokenRef, Text: "@3f2a9c1e-0b4d-4e2a-9f1c-7d8e6a5b4c3d", Pos: 0},
+				{Kind: TokenStar, Text: "*", Pos: 37},
+				{Kind: TokenNumber, Text: "2", Pos: 38},
+				{Kind: TokenEOF, Pos: 39},
+			},
+			expectedErr: nil,
+		},
+		{
+			// минус после ID - вычитание, а не часть ссылки
+			name: "Reference minus number",
+			expr: "@3f2a9c1e-0b4d-4e2a-9f1c-7d8e6a5b4c3d-1",
+			expectedTokens: []Token{
+				{Kind: TokenRef, Text: "@3f2a9c1e-0b4d-4e2a-9f1c-7d8e6a5b4c3d", Pos: 0},
+				{Kind: TokenMinus, Text: "-", Pos: 37},
+				{Kind: TokenNumber, Text: "1", Pos: 38},
+				{Kind: TokenEOF, Pos: 39},
+			},
+			expectedErr: nil,
+		},
+		{
+			name:           "Empty reference",
+			expr:           "@*2",
+			expectedTokens: nil,
+			expectedErr:    models.ErrUnexpectedSymbol,
+		},
+		{
+			name:           "Reference is not an ID",
+			expr:           "@3f2a-9c*2",
+			expectedTokens: nil,
+			expectedErr:    models.ErrUnexpectedSymbol,
+		},
+		{
+			name:           "Unexpected symbol",
+			expr:           "1+2+$",
+			expectedTokens: nil,
+			expectedErr:    models.ErrUnexpectedSymbol,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := Tokenize(tt.expr)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedTokens, tokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package expr
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// приоритеты левоассоциативных бинарных операторов. возведение в степень (^)
+// правоассоциативно, связывает сильнее унарного минуса и разбирается в parsePower
+var binaryPrecedence = map[TokenKind]int{
+	TokenPlus:        1,
+	TokenMinus:       1,
+	TokenStar:        2,
+	TokenSlash:       2,
+	TokenDoubleSlash: 2,
+	TokenPercent:     2,
+}
+
+type parser struct {
+	src    string
+	tokens []Token
+	pos    int
+}
+
+// функция для получения всех бинарных операторов в порядке объявления
+func binaryOperators() []TokenKind {
+	kinds := make([]TokenKind, 0, len(binaryPrecedence)+1)
+	for kind := range binaryPrecedence {
+		kinds = append(kinds, kind)
+	}
+	kinds = append(kinds, TokenCaret)
+	slices.Sort(kinds)
+	return kinds
+}
+
+// ошибка на лексеме t с перечнем ожидаемых вместо нее лексем
+func (p *parser) errorAt(t Token, expected []TokenKind, err error) error {
+	var names []string
+	for _, kind := range expected {
+		names = append(names, kind.describe())
+	}
+	return newParseError(p.src, t.Pos, t.End(), t.describe(), names, err)
+}
+
+// функция для построения синтаксического дерева по выражению
+func Parse(src string) (Node, error) {
+	tokens, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: src, tokens: tokens}
+	node, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.Kind != TokenEOF {
+		return nil, p.errorAt(t, append(binaryOperators(), TokenEOF), models.ErrBadExpression)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() Token {
+	t := p.tokens[p.pos]
+	if t.Kind != TokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// лексема - знак + или -
+func isSign(t Token) bool {
+	return t.Kind == TokenPlus || t.Kind == TokenMinus
+}
+
+// бинарные операции с приоритетом не ниже minPrec (все левоассоциативные)
+func (p *parser) parseBinary(minPrec int) (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := p.peek()
+		prec, ok := binaryPrecedence[op.Kind]
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: op.Text, X: left, Y: right}
+	}
+}
+
+// унарные + и -. связывают сильнее умножения и деления, но слабее степени: -2^2 = -(2^2).
+// знак может стоять после любого бинарного оператора (2*-4, 3 - -2, 1++2),
+// но два унарных знака подряд (--2, 1+-+2) считаются ошибкой
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	if !isSign(t) {
+		return p.parsePower(false)
+	}
+	p.next()
+
+	x, err := p.parsePower(true)
+	if err != nil {
+		return nil, err
+	}
+	return &Unary{Op: t.Text, X: x, From: t.Pos}, nil
+}
+
+// возведение в степень: x ^ y, правоассоциативно (2^3^2 = 2^(3^2)).
+// показатель может начинаться с унарного знака: 2^-1. signed - перед основанием стоит унарный знак
+func (p *parser) parsePower(signed bool) (Node, error) {
+	base, err := p.parsePrimary(signed)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().Kind != TokenCaret {
+		return base, nil
+	}
+	op := p.next()
+
+	exp, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &Binary{Op: op.Text, X: base, Y: exp}, nil
+}
+
+// число, идентификатор, ссылка на результат, вызов функции или выражение в скобках.
+// signed - перед операндом стоит унарный знак, поэтому второй знак не ожидается
+func (p *parser) parsePrimary(signed bool) (Node, error) {
+	t := p.next()
+
+	switch t.Kind {
+	case TokenNumber:
+		value, err := parseNumber(t.Text)
+		if err != nil {
+			return nil, p.errorAt(t, nil, err)
+		}
+		return &Number{Value: value, Text: t.Text, Imag: strings.HasSuffix(t.Text, imagUnit), From: t.Pos}, nil
+
+	case TokenRef:
+		return &Ref{ID: t.Text[1:], From: t.Pos}, nil
+
+	case TokenIdent:
+		if p.peek().Kind != TokenLParen {
+			return &Ident{Name: t.Text, From: t.Pos}, nil
+		}
+		return p.parseCall(t)
+
+	case TokenLParen:
+		x, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		closing := p.next()
+		if closing.Kind != TokenRParen {
+			return nil, p.errorAt(closing, append(binaryOperators(), TokenRParen), models.ErrBadExpression)
+		}
+		return &Paren{X: x, From: t.Pos, To: closing.End()}, nil
+
+	default:
+		expected := []TokenKind{TokenNumber, TokenIdent, TokenRef, TokenLParen}
+		if !signed {
+			expected = append(expected, TokenPlus, TokenMinus)
+		}
+		return nil, p.errorAt(t, expected, models.ErrBadExpression)
+	}
+}
+
+// вызов функции name(arg, ...). лексема name уже прочитана, следующая - "("
+func (p *parser) parseCall(name Token) (Node, error) {
+	fn, ok := Functions[name.Text]
+	if !ok {
+		return nil, p.errorAt(name, nil, models.ErrUnknownFunction)
+	}
+	p.next()
+
+	var args []Node
+	if p.peek().Kind != TokenRParen {
+		for {
+			arg, err := p.parseBinary(1)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			if p.peek().Kind != TokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	closing := p.next()
+	if closing.Kind != TokenRParen {
+		return nil, p.errorAt(closing, append(binaryOperators(), TokenComma, TokenRParen), models.ErrBadExpression)
+	}
+
+	if !fn.accepts(len(args)) {
+		token := fmt.Sprintf("%s in %q", pluralArgs(len(args)), name.Text)
+		return nil, newParseError(p.src, name.Pos, closing.End(), token, []string{fn.describeArity()}, models.ErrArgumentCount)
+	}
+	return &Call{Name: name.Text, Args: args, From: name.Pos, To: closing.End()}, nil
+}
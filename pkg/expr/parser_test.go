@@ -0,0 +1,412 @@
+package expr
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// тесты для Parse
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		expr         string
+		expectedTree string
+		expectedErr  error
+	}{
+		{
+			name:         "Precedence",
+			expr:         "1+2*3",
+			expectedTree: "(1 + (2 * 3))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Left associativity",
+			expr:         "8-4-2",
+			expectedTree: "((8 - 4) - 2)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Parentheses",
+			expr:         "1*2+(3+4-5+(6/2))",
+			expectedTree: "((1 * 2) + (((3 + 4) - 5) + (6 / 2)))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unary minus",
+			expr:         "-3+5",
+			expectedTree: "((-3) + 5)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unary minus after operator",
+			expr:         "2*-4",
+			expectedTree: "(2 * (-4))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unary minus after minus",
+			expr:         "3 - -2",
+			expectedTree: "(3 - (-2))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unary minus after plus",
+			expr:         "1+-2",
+			expectedTree: "(1 + (-2))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unary plus after plus",
+			expr:         "1++2",
+			expectedTree: "(1 + (+2))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unary minus before parenthesis",
+			expr:         "-(1+2)*3",
+			expectedTree: "((-(1 + 2)) * 3)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unary plus",
+			expr:         "+2-(+3)",
+			expectedTree: "((+2) - (+3))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Power is right-associative",
+			expr:         "2^3^2",
+			expectedTree: "(2 ^ (3 ^ 2))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Power binds tighter than unary minus",
+			expr:         "-2^2",
+			expectedTree: "(-(2 ^ 2))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Power of negative base",
+			expr:         "(-2)^2",
+			expectedTree: "((-2) ^ 2)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Negative exponent",
+			expr:         "2^-1*3",
+			expectedTree: "((2 ^ (-1)) * 3)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Modulo and integer division",
+			expr:         "7%3+7//2*2",
+			expectedTree: "((7 % 3) + ((7 // 2) * 2))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Functions",
+			expr:         "sqrt(2)*max(3,4,5)",
+			expectedTree: "(sqrt(2) * max(3, 4, 5))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Nested functions",
+			expr:         "-abs(round(2.5)-min(1, -cos(0)))",
+			expectedTree: "(-abs((round(2.5) - min(1, (-cos(0))))))",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Reference",
+			expr:         "@1b2c3d4e-5f60-4a1b-8c2d-3e4f5a6b7c8d * 2 + x",
+			expectedTree: "((@1b2c3d4e-5f60-4a1b-8c2d-3e4f5a6b7c8d * 2) + x)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Unknown function",
+			expr:         "foo(1)",
+			expectedTree: "",
+			expectedErr:  models.ErrUnknownFunction,
+		},
+		{
+			name:         "Too many arguments",
+			expr:         "sqrt(1, 2)",
+			expectedTree: "",
+			expectedErr:  models.ErrArgumentCount,
+		},
+		{
+			name:         "No arguments to variadic function",
+			expr:         "max()",
+			expectedTree: "",
+			expectedErr:  models.ErrArgumentCount,
+		},
+		{
+			name:         "Trailing comma",
+			expr:         "max(1,)",
+			expectedTree: "",
+			expectedErr:  models.ErrBadExpression,
+		},
+		{
+			name:         "Dangling power",
+			expr:         "2^",
+			expectedTree: "",
+			expectedErr:  models.ErrBadExpression,
+		},
+		{
+			name:         "Double sign",
+			expr:         "1+--2",
+			expectedTree: "",
+			expectedErr:  models.ErrBadExpression,
+		},
+		{
+			name:         "Double unary minus",
+			expr:         "--2",
+			expectedTree: "",
+			expectedErr:  models.ErrBadExpression,
+		},
+		{
+			name:         "Unclosed parenthesis",
+			expr:         "(1+2",
+			expectedTree: "",
+			expectedErr:  models.ErrBadExpression,
+		},
+		{
+			name:         "Extra closing parenthesis",
+			expr:         "1+2)",
+			expectedTree: "",
+			expectedErr:  models.ErrBadExpression,
+		},
+		{
+			name:         "Empty expression",
+			expr:         "",
+			expectedTree: "",
+			expectedErr:  models.ErrBadExpression,
+		},
+		{
+			name:         "Imaginary numbers",
+			expr:         "(2+3i)*(1-i)-2.5e1i",
+			expectedTree: "(((2 + 3i) * (1 - i)) - 2.5e1i)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Identifier starting with i",
+			expr:         "2*in",
+			expectedTree: "(2 * in)",
+			expectedErr:  nil,
+		},
+		{
+			name:         "Bad number",
+			expr:         "1.2.3+1",
+			expectedTree: "",
+			expectedErr:  models.ErrBadNumber,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := Parse(tt.expr)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedTree, String(tree))
+			}
+		})
+	}
+}
+
+// позиции узлов должны указывать на исходный текст
+func TestParse_Positions(t *testing.T) {
+	src := "12 * -(3*4)"
+	tree, err := Parse(src)
+	assert.NoError(t, err)
+
+	bin, ok := tree.(*Binary)
+	assert.True(t, ok)
+	assert.Equal(t, "12", src[bin.X.Pos():bin.X.End()])
+	assert.Equal(t, "-(3*4)", src[bin.Y.Pos():bin.Y.End()])
+
+	neg, ok := bin.Y.(*Unary)
+	assert.True(t, ok)
+	assert.Equal(t, "3*4", src[Unparen(neg.X).Pos():Unparen(neg.X).End()])
+}
+
+// тесты для ошибок разбора с позицией
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name             string
+		expr             string
+		expectedOffset   int
+		expectedLength   int
+		expectedToken    string
+		expectedExpected []string
+		expectedSnippet  string
+		expectedErr      error
+	}{
+		{
+			name:             "Unexpected symbol",
+			expr:             "1+2+$",
+			expectedOffset:   4,
+			expectedLength:   1,
+			expectedToken:    `"$"`,
+			expectedExpected: nil,
+			expectedSnippet:  "1+2+$\n    ^",
+			expectedErr:      models.ErrUnexpectedSymbol,
+		},
+		{
+			name:             "Double sign",
+			expr:             "1+--2",
+			expectedOffset:   3,
+			expectedLength:   1,
+			expectedToken:    `"-"`,
+			expectedExpected: []string{"number", "identifier", "reference", `"("`},
+			expectedSnippet:  "1+--2\n   ^",
+			expectedErr:      models.ErrBadExpression,
+		},
+		{
+			name:             "Bad number",
+			expr:             "2*1.2.3",
+			expectedOffset:   2,
+			expectedLength:   5,
+			expectedToken:    `"1.2.3"`,
+			expectedExpected: nil,
+			expectedSnippet:  "2*1.2.3\n  ^^^^^",
+			expectedErr:      models.ErrBadNumber,
+		},
+		{
+			name:             "Unexpected end",
+			expr:             "(1+2",
+			expectedOffset:   4,
+			expectedLength:   0,
+			expectedToken:    "end of expression",
+			expectedExpected: []string{`"+"`, `"-"`, `"*"`, `"/"`, `"//"`, `"%"`, `"^"`, `")"`},
+			expectedSnippet:  "(1+2\n    ^",
+			expectedErr:      models.ErrBadExpression,
+		},
+		{
+			name:             "Wrong number of arguments",
+			expr:             "1+sqrt(2, 3)",
+			expectedOffset:   2,
+			expectedLength:   10,
+			expectedToken:    `2 arguments in "sqrt"`,
+			expectedExpected: []string{"1 argument"},
+			expectedSnippet:  "1+sqrt(2, 3)\n  ^^^^^^^^^^",
+			expectedErr:      models.ErrArgumentCount,
+		},
+		{
+			name:             "Non-ASCII symbol",
+			expr:             "2·3",
+			expectedOffset:   1,
+			expectedLength:   1,
+			expectedToken:    `"·"`,
+			expectedExpected: nil,
+			expectedSnippet:  "2·3\n ^",
+			expectedErr:      models.ErrUnexpectedSymbol,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			var perr *ParseError
+			if assert.ErrorAs(t, err, &perr) {
+				assert.Equal(t, tt.expectedOffset, perr.Offset)
+				assert.Equal(t, tt.expectedLength, perr.Length)
+				assert.Equal(t, tt.expectedToken, perr.Token)
+				assert.Equal(t, tt.expectedExpected, perr.Expected)
+				assert.Equal(t, tt.expectedSnippet, perr.Snippet(tt.expr))
+			}
+		})
+	}
+}
+
+// тесты для Bind
+func TestBind(t *testing.T) {
+	src := "a*x+b+pi"
+	tree, err := Parse(src)
+	assert.NoError(t, err)
+
+	bindings, err := Bind(src, tree, map[string]float64{"a": 2, "x": 3, "b": 1, "unused": 5}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"a": 2, "x": 3, "b": 1, "pi": math.Pi}, bindings)
+
+	// переменные перекрывают константы
+	bindings, err = Bind(src, tree, map[string]float64{"a": 2, "x": 3, "b": 1, "pi": 3}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, bindings["pi"])
+
+	_, err = Bind(src, tree, map[string]float64{"a": 2, "b": 1}, nil)
+	assert.ErrorIs(t, err, models.ErrUndefinedVariable)
+	var perr *ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 2, perr.Offset)
+		assert.Equal(t, 1, perr.Length)
+		assert.Equal(t, `"x"`, perr.Token)
+		assert.Equal(t, "a*x+b+pi\n  ^", perr.Snippet(src))
+	}
+}
+
+// мнимые числа вне режима complex - ошибка с позицией числа
+func TestRequireReal(t *testing.T) {
+	src := "1+2*3i"
+	tree, err := Parse(src)
+	assert.NoError(t, err)
+
+	num := Unparen(tree.(*Binary).Y.(*Binary).Y).(*Number)
+	assert.True(t, num.Imag)
+	assert.Equal(t, 3.0, num.Value)
+
+	err = RequireReal(src, tree)
+	assert.ErrorIs(t, err, models.ErrImaginaryNumber)
+	var perr *ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 4, perr.Offset)
+		assert.Equal(t, 2, perr.Length)
+	}
+
+	tree, err = Parse("1+2*3")
+	assert.NoError(t, err)
+	assert.NoError(t, RequireReal("1+2*3", tree))
+	assert.False(t, IsIdentifier("i"))
+}
+
+// ссылки на результаты связываются через results
+func TestBind_References(t *testing.T) {
+	const abc, def = "0a1b2c3d-0000-4000-8000-00000000000a", "0a1b2c3d-0000-4000-8000-00000000000d"
+	src := "@" + abc + "*2+@" + def
+	tree, err := Parse(src)
+	assert.NoError(t, err)
+
+	results := func(id string) (float64, error) {
+		if id == abc {
+			return 21, nil
+		}
+		return 0, models.ErrReferencePending
+	}
+
+	_, err = Bind(src, tree, nil, results)
+	assert.ErrorIs(t, err, models.ErrReferencePending)
+	var perr *ParseError
+	if assert.ErrorAs(t, err, &perr) {
+		assert.Equal(t, 40, perr.Offset)
+		assert.Equal(t, 37, perr.Length)
+	}
+
+	// значения ссылок из vars не обходят проверку results
+	_, err = Bind(src, tree, map[string]float64{"@" + def: 1}, results)
+	assert.ErrorIs(t, err, models.ErrReferencePending)
+
+	// без results берутся сохраненные значения, остальные ссылки пропускаются
+	bindings, err := Bind(src, tree, map[string]float64{"@" + def: 1}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"@" + def: 1}, bindings)
+
+	bindings, err = Bind(src, tree, nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, bindings)
+}
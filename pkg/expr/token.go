@@ -0,0 +1,78 @@
+package expr
+
+import (
+	"fmt"
+)
+
+// вид лексемы
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenNumber
+	TokenIdent
+	TokenRef
+	TokenPlus
+	TokenMinus
+	TokenStar
+	TokenSlash
+	TokenDoubleSlash
+	TokenPercent
+	TokenCaret
+	TokenLParen
+	TokenRParen
+	TokenComma
+)
+
+var tokenNames = map[TokenKind]string{
+	TokenEOF:         "end of expression",
+	TokenNumber:      "number",
+	TokenIdent:       "identifier",
+	TokenRef:         "reference",
+	TokenPlus:        "+",
+	TokenMinus:       "-",
+	TokenStar:        "*",
+	TokenSlash:       "/",
+	TokenDoubleSlash: "//",
+	TokenPercent:     "%",
+	TokenCaret:       "^",
+	TokenLParen:      "(",
+	TokenRParen:      ")",
+	TokenComma:       ",",
+}
+
+func (k TokenKind) String() string {
+	if name, ok := tokenNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// название вида лексемы для сообщений об ошибках: знаки берутся в кавычки
+func (k TokenKind) describe() string {
+	switch k {
+	case TokenEOF, TokenNumber, TokenIdent, TokenRef:
+		return k.String()
+	}
+	return fmt.Sprintf("%q", k.String())
+}
+
+// лексема с позицией (смещение в байтах от начала выражения)
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+// описание лексемы для сообщений об ошибках
+func (t Token) describe() string {
+	if t.Kind == TokenEOF {
+		return t.Kind.String()
+	}
+	return fmt.Sprintf("%q", t.Text)
+}
+
+// позиция сразу после лексемы
+func (t Token) End() int {
+	return t.Pos + len(t.Text)
+}
@@ -10,22 +10,63 @@ var (
 	ErrSubtracrionTime    = errors.New("environment variable for subtraction wasn't set correctly")
 	ErrMultiplicationTime = errors.New("environment variable for multiplication wasn't set correctly")
 	ErrDivisionTime       = errors.New("environment variable for division wasn't set correctly")
+	ErrPowerTime          = errors.New("environment variable for exponentiation wasn't set correctly")
+	ErrModuloTime         = errors.New("environment variable for modulo wasn't set correctly")
+	ErrIntDivisionTime    = errors.New("environment variable for integer division wasn't set correctly")
+	ErrFunctionTime       = errors.New("environment variable for function wasn't set correctly")
 	ErrTableFormat        = errors.New("environment variable TABLE_FORMAT wasn't set correctly")
+	ErrNumericModeEnv     = errors.New("environment variable NUMERIC_MODE wasn't set correctly")
+	ErrDecimalScale       = errors.New("environment variable DECIMAL_SCALE wasn't set correctly")
+	ErrDecimalRounding    = errors.New("environment variable DECIMAL_ROUNDING wasn't set correctly")
+	ErrTimeoutEnv         = errors.New("environment variable for timeout wasn't set correctly")
+	ErrRetryEnv           = errors.New("environment variable for retry policy wasn't set correctly")
+	ErrCacheEnv           = errors.New("environment variable for result cache wasn't set correctly")
+	ErrLocalEnv           = errors.New("environment variable for local evaluation wasn't set correctly")
 
 	// ошибки в математическом выражении:
-	ErrDivisionByZero   = errors.New("division by zero")
-	ErrUnexpectedSymbol = errors.New("unexpected symbol")
-	ErrBadExpression    = errors.New("incorrect expression")
+	ErrDivisionByZero     = errors.New("division by zero")
+	ErrModuloByZero       = errors.New("modulo by zero")
+	ErrBadPower           = errors.New("power is undefined for these arguments")
+	ErrSqrtOfNegative     = errors.New("square root of negative number")
+	ErrLogOfNonPositive   = errors.New("logarithm of non-positive number")
+	ErrOverflow           = errors.New("result is out of range")
+	ErrUnknownFunction    = errors.New("unknown function")
+	ErrUndefinedVariable  = errors.New("undefined variable")
+	ErrBadVariableName    = errors.New("incorrect variable name")
+	ErrReferenceNotFound  = errors.New("referenced expression not found")
+	ErrReferencePending   = errors.New("referenced expression is not completed yet")
+	ErrReferenceFailed    = errors.New("referenced expression failed")
+	ErrReferenceCancelled = errors.New("referenced expression was cancelled")
+	ErrReferenceTimeout   = errors.New("referenced expression timed out")
+	ErrArgumentCount      = errors.New("wrong number of function arguments")
+	ErrUnexpectedSymbol   = errors.New("unexpected symbol")
+	ErrBadExpression      = errors.New("incorrect expression")
+	ErrBadNumber          = errors.New("incorrect number")
+	ErrUnknownMode        = errors.New("unknown numeric mode")
+	ErrUnsupportedOp      = errors.New("operation is not supported in this numeric mode")
+	ErrImaginaryNumber    = errors.New("imaginary numbers are only supported in complex mode")
 
 	// ошибки grpc
 	ErrStartingListener = errors.New("error starting tcp listener")
 	ErrServingGRPC      = errors.New("error serving grpc")
 	ErrConnectingGRPC   = errors.New("could not connect to grpc server")
+	ErrTaskNotFound     = errors.New("can't find task")
+	ErrNoAgents         = errors.New("no available agents")
+	ErrAgentNotFound    = errors.New("agent is not registered")
+	ErrAgentBusy        = errors.New("agent queue is full")
+	ErrModeNotSupported = errors.New("agent does not support numeric mode")
+	ErrDispatchMode     = errors.New("environment variable DISPATCH_MODE wasn't set correctly")
+	ErrAgentMode        = errors.New("environment variable AGENT_MODE wasn't set correctly")
 
 	// ошибки database
-	ErrDatabaseCreating = errors.New("error creating sql database")
-	ErrPingContext      = errors.New("database not active")
-	ErrCannotFindObject = errors.New("can't find expression")
+	ErrDatabaseCreating  = errors.New("error creating sql database")
+	ErrPingContext       = errors.New("database not active")
+	ErrCannotFindObject  = errors.New("can't find expression")
+	ErrCancelled         = errors.New("expression was cancelled")
+	ErrNotRunning        = errors.New("expression is already finished")
+	ErrOperationTimeout  = errors.New("operation timed out")
+	ErrExpressionTimeout = errors.New("expression timed out")
+	ErrVariableNotFound  = errors.New("can't find variable")
 
 	// ошибки auth
 	ErrIncorrectPassword = errors.New("incorrecct password")
@@ -33,3 +74,28 @@ var (
 	ErrUserNotRegistered = errors.New("user is not registered")
 	ErrInvalidToken      = errors.New("invalid token")
 )
+
+// ошибки вычисления операций агентом. передаются оркестратору текстом (см. ErrorFromText)
+var calcErrors = []error{
+	ErrDivisionByZero,
+	ErrModuloByZero,
+	ErrBadPower,
+	ErrSqrtOfNegative,
+	ErrLogOfNonPositive,
+	ErrOverflow,
+	ErrUnexpectedSymbol,
+	ErrUnsupportedOp,
+	ErrBadNumber,
+	ErrOperationTimeout,
+}
+
+// функция для восстановления ошибки вычисления по ее тексту,
+// чтобы после передачи по сети ее можно было проверить через errors.Is
+func ErrorFromText(text string) error {
+	for _, err := range calcErrors {
+		if err.Error() == text {
+			return err
+		}
+	}
+	return errors.New(text)
+}
@@ -4,15 +4,114 @@ const (
 	StatusPending   = "pending"
 	StatusCompleted = "completed"
 	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusTimeout   = "timeout"
 )
 
+// режимы вычисления: float - числа float64, decimal - точная десятичная арифметика,
+// rational - точные дроби, complex - комплексные числа
+const (
+	ModeFloat    = "float"
+	ModeDecimal  = "decimal"
+	ModeRational = "rational"
+	ModeComplex  = "complex"
+)
+
+// способы округления результата деления в режиме decimal
+const (
+	RoundHalfEven = "half_even"
+	RoundHalfUp   = "half_up"
+	RoundHalfDown = "half_down"
+	RoundUp       = "up"
+	RoundDown     = "down"
+	RoundCeiling  = "ceiling"
+	RoundFloor    = "floor"
+)
+
+// способы передачи операций агентам
+const (
+	DispatchPush = "push"
+	DispatchPull = "pull"
+)
+
+// откуда взят результат шага выражения: вычислен агентом, взят из кэша
+// или вычислен самим оркестратором
+const (
+	SourceAgent = "agent"
+	SourceCache = "cache"
+	SourceLocal = "local"
+)
+
+// режимы работы агента
+const (
+	AgentServer = "server"
+	AgentPull   = "pull"
+)
+
+// переменные среды со временем выполнения встроенных функций (в мс)
+var FunctionTimeEnvs = map[string]string{
+	"sqrt":  "TIME_SQRT_MS",
+	"sin":   "TIME_SIN_MS",
+	"cos":   "TIME_COS_MS",
+	"log":   "TIME_LOG_MS",
+	"abs":   "TIME_ABS_MS",
+	"round": "TIME_ROUND_MS",
+	"min":   "TIME_MIN_MS",
+	"max":   "TIME_MAX_MS",
+}
+
+// структура запроса на вычисление выражения
+type CalcRequest struct {
+	Expression string             `json:"expression"`
+	Variables  map[string]float64 `json:"variables,omitempty"`
+	Mode       string             `json:"mode,omitempty"`
+	// не брать результаты операций из кэша (вычисленные результаты все равно сохраняются в кэш)
+	NoCache bool `json:"no_cache,omitempty"`
+}
+
 // структура для состояния выражения
 type Expression struct {
-	ID     string  `json:"id"`
-	Expr   string  `json:"expression"`
-	Status string  `json:"status"`
-	Result float64 `json:"result"`
-	Error  string  `json:"error"`
+	ID        string             `json:"id"`
+	Expr      string             `json:"expression"`
+	Variables map[string]float64 `json:"variables,omitempty"`
+	Mode      string             `json:"mode,omitempty"`
+	Status    string             `json:"status"`
+	Result    float64            `json:"result"`
+	// точная запись результата в режимах, отличных от float
+	Value string `json:"value,omitempty"`
+	// в режиме rational - числитель и знаменатель результата и его десятичное приближение
+	Fraction *Fraction `json:"fraction,omitempty"`
+	Decimal  string    `json:"decimal,omitempty"`
+	// в режиме complex - действительная и мнимая части результата
+	Complex *Complex `json:"complex,omitempty"`
+	Error   string   `json:"error"`
+	// число попыток вычисления операций агентами, включая повторные (см. RETRY_MAX_ATTEMPTS)
+	Attempts int `json:"attempts,omitempty"`
+	// вычисленные шаги (операции) выражения
+	Steps []Step `json:"steps,omitempty"`
+}
+
+// шаг выражения: операция, ее аргументы и результат
+type Step struct {
+	Step      int    `json:"step"`
+	Operation string `json:"operation"`
+	Arg1      string `json:"arg1"`
+	Arg2      string `json:"arg2"`
+	Result    string `json:"result"`
+	// откуда взят результат (см. models.Source*)
+	Source string `json:"source"`
+}
+
+// результат в виде несократимой дроби
+type Fraction struct {
+	Numerator   string `json:"numerator"`
+	Denominator string `json:"denominator"`
+}
+
+// комплексный результат
+type Complex struct {
+	Real float64 `json:"real"`
+	Imag float64 `json:"imag"`
 }
 
 // структура задачи
@@ -23,6 +122,49 @@ type Task struct {
 	Arg2      float64 `json:"arg2"`
 	Operation string  `json:"operation"`
 
+	// режим вычисления и точная запись аргументов и результата (кроме режима float)
+	Mode        string `json:"mode,omitempty"`
+	Arg1Exact   string `json:"arg1_exact,omitempty"`
+	Arg2Exact   string `json:"arg2_exact,omitempty"`
+	ResultExact string `json:"result_exact,omitempty"`
+
+	// мнимые части аргументов и результата в режиме complex
+	Arg1Imag   float64  `json:"arg1_imag,omitempty"`
+	Arg2Imag   float64  `json:"arg2_imag,omitempty"`
+	ResultImag *float64 `json:"result_imag,omitempty"`
+
 	Result float64 `json:"result,omitempty"`
 	Error  string  `json:"error,omitempty"`
 }
+
+// структура агента, зарегистрированного у оркестратора
+type Agent struct {
+	ID             string   `json:"id"`
+	Address        string   `json:"address,omitempty"`
+	ComputingPower int      `json:"computing_power"`
+	Operations     []string `json:"operations"`
+	Version        string   `json:"version"`
+
+	// загрузка агента из последнего Heartbeat
+	Load AgentLoad `json:"load"`
+}
+
+// загрузка агента: воркеры и очередь операций
+type AgentLoad struct {
+	Workers     int `json:"workers"`
+	BusyWorkers int `json:"busy_workers"`
+	QueueDepth  int `json:"queue_depth"`
+	QueueSize   int `json:"queue_size"`
+}
+
+// статистика кэша результатов операций оркестратора
+type CacheStats struct {
+	Enabled   bool    `json:"enabled"`
+	Size      int     `json:"size"`
+	Capacity  int     `json:"capacity"`
+	TTLMs     int     `json:"ttl_ms"`
+	Hits      uint64  `json:"hits"`
+	Misses    uint64  `json:"misses"`
+	Evictions uint64  `json:"evictions"`
+	HitRate   float64 `json:"hit_rate"`
+}
@@ -0,0 +1,154 @@
+package numeric
+
+import (
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// функция для записи комплексного числа: 2+3i, 2-1i, 3i, 2
+func formatComplex(c complex128) string {
+	re, im := real(c), imag(c)
+	if im == 0 {
+		return strconv.FormatFloat(re, 'g', -1, 64)
+	}
+	imText := strconv.FormatFloat(im, 'g', -1, 64) + "i"
+	if re == 0 {
+		return imText
+	}
+	if im > 0 || math.IsNaN(im) {
+		imText = "+" + imText
+	}
+	return strconv.FormatFloat(re, 'g', -1, 64) + imText
+}
+
+// функция для разбора комплексного числа: 2+3i, 3i, i, 2
+func parseComplex(s string) (complex128, error) {
+	// коэффициент 1 при мнимой единице может быть опущен: i, -i, 2+i
+	if strings.HasSuffix(s, "i") && (len(s) == 1 || strings.ContainsRune("+-", rune(s[len(s)-2]))) {
+		s = s[:len(s)-1] + "1i"
+	}
+	c, err := strconv.ParseComplex(s, 128)
+	if err != nil {
+		return 0, models.ErrBadNumber
+	}
+	return c, nil
+}
+
+// комплексное число и его запись. Float - действительная часть
+func complexValue(c complex128) Value {
+	return Value{Float: real(c), Imag: imag(c), Exact: formatComplex(c)}
+}
+
+// число по действительной и мнимой частям
+func Complex(re, im float64) Value {
+	return complexValue(complex(re, im))
+}
+
+// действительная и мнимая части результата в режиме complex (nil в остальных режимах)
+func ComplexParts(mode string, v Value) *models.Complex {
+	if mode != models.ModeComplex {
+		return nil
+	}
+	c, err := parseComplex(v.Exact)
+	if err != nil {
+		return nil
+	}
+	return &models.Complex{Real: real(c), Imag: imag(c)}
+}
+
+// проверка результата: NaN и бесконечности - ошибка err
+func finiteComplex(c complex128, err error) (Value, error) {
+	if cmplx.IsNaN(c) || cmplx.IsInf(c) {
+		return Value{}, err
+	}
+	return complexValue(c), nil
+}
+
+// целая степень умножениями: в отличие от cmplx.Pow, i^2 = -1 без погрешности
+func powInt(x complex128, n int) complex128 {
+	if n < 0 {
+		return 1 / powInt(x, -n)
+	}
+	res := complex(1, 0)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			res *= x
+		}
+		x *= x
+	}
+	return res
+}
+
+// вычисление операции над комплексными числами. //, %, min и max определены
+// только для действительных чисел
+func calculateComplex(op string, xv, yv Value) (Value, error) {
+	x, y := complex(xv.Float, xv.Imag), complex(yv.Float, yv.Imag)
+	isReal := imag(x) == 0 && imag(y) == 0
+
+	switch op {
+	case "+":
+		return finiteComplex(x+y, models.ErrOverflow)
+	case "-":
+		return finiteComplex(x-y, models.ErrOverflow)
+	case "*":
+		return finiteComplex(x*y, models.ErrOverflow)
+	case "/":
+		if y == 0 {
+			return Value{}, models.ErrDivisionByZero
+		}
+		return finiteComplex(x/y, models.ErrOverflow)
+	case "^":
+		if x == 0 && (imag(y) != 0 || real(y) < 0) {
+			return Value{}, models.ErrBadPower
+		}
+		if n := real(y); imag(y) == 0 && n == math.Trunc(n) && math.Abs(n) <= maxExponent {
+			return finiteComplex(powInt(x, int(n)), models.ErrBadPower)
+		}
+		return finiteComplex(cmplx.Pow(x, y), models.ErrBadPower)
+	case "sqrt":
+		// корень из отрицательного числа - мнимое число: sqrt(-4) = 2i
+		return complexValue(cmplx.Sqrt(x)), nil
+	case "sin":
+		return finiteComplex(cmplx.Sin(x), models.ErrBadNumber)
+	case "cos":
+		return finiteComplex(cmplx.Cos(x), models.ErrBadNumber)
+	case "log":
+		if x == 0 {
+			return Value{}, models.ErrLogOfNonPositive
+		}
+		return complexValue(cmplx.Log(x)), nil
+	case "abs":
+		return finiteComplex(complex(cmplx.Abs(x), 0), models.ErrOverflow)
+	case "round":
+		return complexValue(complex(math.Round(xv.Float), math.Round(xv.Imag))), nil
+	case "//", "%", "min", "max":
+		if !isReal {
+			return Value{}, models.ErrUnsupportedOp
+		}
+	default:
+		return Value{}, models.ErrUnexpectedSymbol
+	}
+
+	// операции над действительными числами, как в режиме float
+	a, b := xv.Float, yv.Float
+	switch op {
+	case "//":
+		if b == 0 {
+			return Value{}, models.ErrDivisionByZero
+		}
+		return Complex(math.Floor(a/b), 0), nil
+	case "%":
+		if b == 0 {
+			return Value{}, models.ErrModuloByZero
+		}
+		return Complex(floorMod(a, b), 0), nil
+	case "min":
+		return Complex(min(a, b), 0), nil
+	default:
+		return Complex(max(a, b), 0), nil
+	}
+}
@@ -0,0 +1,74 @@
+package numeric
+
+import (
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// тесты для Calculate в режиме complex
+func TestCalculate_Complex(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          string
+		x, y        Value
+		expectedRes string
+		expectedErr error
+	}{
+		{name: "Multiplication", op: "*", x: Complex(2, 3), y: Complex(1, -1), expectedRes: "5+1i"},
+		{name: "Subtraction", op: "-", x: Complex(1, 0), y: Complex(0, 1), expectedRes: "1-1i"},
+		{name: "Division", op: "/", x: Complex(0, 2), y: Complex(1, 1), expectedRes: "1+1i"},
+		{name: "Division by zero", op: "/", x: Complex(1, 1), y: Complex(0, 0), expectedErr: models.ErrDivisionByZero},
+		{name: "Square root of negative", op: "sqrt", x: Complex(-4, 0), expectedRes: "2i"},
+		{name: "Power", op: "^", x: Complex(0, 1), y: Complex(2, 0), expectedRes: "-1"},
+		{name: "Zero to negative power", op: "^", x: Complex(0, 0), y: Complex(-1, 0), expectedErr: models.ErrBadPower},
+		{name: "Absolute value", op: "abs", x: Complex(3, -4), expectedRes: "5"},
+		{name: "Logarithm of zero", op: "log", x: Complex(0, 0), expectedErr: models.ErrLogOfNonPositive},
+		{name: "Round", op: "round", x: Complex(1.4, -2.5), expectedRes: "1-3i"},
+		{name: "Real modulo", op: "%", x: Complex(7, 0), y: Complex(3, 0), expectedRes: "1"},
+		{name: "Negative real modulo", op: "%", x: Complex(-7, 0), y: Complex(3, 0), expectedRes: "2"},
+		{name: "Complex modulo", op: "%", x: Complex(7, 1), y: Complex(3, 0), expectedErr: models.ErrUnsupportedOp},
+		{name: "Complex max", op: "max", x: Complex(1, 1), y: Complex(2, 0), expectedErr: models.ErrUnsupportedOp},
+		{name: "Addition overflow", op: "+", x: Complex(1e308, 0), y: Complex(1e308, 0), expectedErr: models.ErrOverflow},
+		{name: "Multiplication overflow", op: "*", x: Complex(1e308, 1), y: Complex(0, 10), expectedErr: models.ErrOverflow},
+		{name: "Division overflow", op: "/", x: Complex(1e308, 1e308), y: Complex(1e-10, 0), expectedErr: models.ErrOverflow},
+		{name: "Absolute value overflow", op: "abs", x: Complex(1.5e308, 1.5e308), expectedErr: models.ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Calculate(models.ModeComplex, tt.op, tt.x, tt.y, Options{})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRes, res.Exact)
+		})
+	}
+}
+
+// тесты для записи комплексных чисел
+func TestComplexLiteral(t *testing.T) {
+	for text, expected := range map[string]Value{
+		"i":      {Float: 0, Imag: 1, Exact: "1i"},
+		"3i":     {Float: 0, Imag: 3, Exact: "3i"},
+		"2.5e1i": {Float: 0, Imag: 25, Exact: "25i"},
+		"2":      {Float: 2, Imag: 0, Exact: "2"},
+		"2-i":    {Float: 2, Imag: -1, Exact: "2-1i"},
+	} {
+		v, err := Literal(models.ModeComplex, text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, v, text)
+	}
+
+	assert.Equal(t, "-2-1i", Neg(models.ModeComplex, Complex(2, 1)).Exact)
+
+	// унарный минус не дает -0 в мнимой части: sqrt(-4) = 2i, а не -2i
+	res, err := Calculate(models.ModeComplex, "sqrt", Neg(models.ModeComplex, Complex(4, 0)), Complex(0, 0), Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "2i", res.Exact)
+	assert.Equal(t, &models.Complex{Real: 5, Imag: 1}, ComplexParts(models.ModeComplex, Value{Exact: "5+1i"}))
+	assert.Nil(t, ComplexParts(models.ModeFloat, Value{Float: 5}))
+}
@@ -0,0 +1,135 @@
+package numeric
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// наибольший показатель степени в записи числа (1e1000) и в операции ^.
+// ограничивает размер чисел, которые приходится хранить точно
+const maxExponent = 1000
+
+// наибольший размер числителя и знаменателя результата ^ в битах (около 20000 десятичных
+// знаков). ограничивает цепочки степеней: (10^1000)^1000
+const maxResultBits = 1 << 16
+
+var (
+	intTwo  = big.NewInt(2)
+	intFive = big.NewInt(5)
+	intTen  = big.NewInt(10)
+)
+
+// функция для разбора десятичной записи: 12, -0.5, 1.5e-3
+func parseDecimal(s string) (*big.Rat, error) {
+	mantissa, exp, hasExp := strings.Cut(strings.ToLower(s), "e")
+	if mantissa == "" || strings.Contains(s, "/") {
+		return nil, models.ErrBadNumber
+	}
+	if hasExp {
+		e, err := strconv.Atoi(exp)
+		if err != nil || e > maxExponent || e < -maxExponent {
+			return nil, models.ErrBadNumber
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, models.ErrBadNumber
+	}
+	return r, nil
+}
+
+// точное значение и его приближение
+func decimalValue(r *big.Rat) Value {
+	f, _ := r.Float64()
+	return Value{Float: f, Exact: formatDecimal(r)}
+}
+
+// функция для получения десятичной записи без лишних нулей: 2.50 -> 2.5, 3.0 -> 3.
+// знаменатель числа должен раскладываться на 2 и 5 (конечная десятичная дробь)
+func formatDecimal(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	// число знаков после запятой - наибольшая из степеней 2 и 5 в знаменателе
+	den := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	for new(big.Int).Rem(den, intTwo).Sign() == 0 {
+		den.Quo(den, intTwo)
+		twos++
+	}
+	for new(big.Int).Rem(den, intFive).Sign() == 0 {
+		den.Quo(den, intFive)
+		fives++
+	}
+
+	s := r.FloatString(max(twos, fives))
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// функция для округления до scale знаков после запятой способом rounding
+func roundDecimal(r *big.Rat, scale int, rounding string) *big.Rat {
+	unit := new(big.Int).Exp(intTen, big.NewInt(int64(scale)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(unit))
+
+	q, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		// сравнение отброшенной части с половиной: 2*|rem| и знаменатель
+		half := new(big.Int).Mul(new(big.Int).Abs(rem), intTwo).Cmp(scaled.Denom())
+		sign := r.Sign()
+
+		var away bool
+		switch rounding {
+		case models.RoundUp:
+			away = true
+		case models.RoundDown:
+			away = false
+		case models.RoundCeiling:
+			away = sign > 0
+		case models.RoundFloor:
+			away = sign < 0
+		case models.RoundHalfUp:
+			away = half >= 0
+		case models.RoundHalfDown:
+			away = half > 0
+		default:
+			away = half > 0 || half == 0 && q.Bit(0) == 1
+		}
+		if away {
+			q.Add(q, big.NewInt(int64(sign)))
+		}
+	}
+
+	return new(big.Rat).SetFrac(q, unit)
+}
+
+// целая часть с округлением вниз (знаменатель всегда положителен, поэтому
+// евклидово деление совпадает с округлением вниз)
+func floorRat(r *big.Rat) *big.Int {
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+// вычисление операции над десятичными числами. +, -, *, //, %, abs, min, max и целые степени
+// вычисляются точно, результаты деления, корня и отрицательной степени округляются
+// до opts.Scale знаков после запятой способом opts.Rounding
+func calculateDecimal(op string, xv, yv Value, opts Options) (Value, error) {
+	x, err := parseDecimal(xv.Exact)
+	if err != nil {
+		return Value{}, err
+	}
+	y, err := parseDecimal(yv.Exact)
+	if err != nil {
+		return Value{}, err
+	}
+
+	res, err := calculateRat(op, x, y, &opts)
+	if err != nil {
+		return Value{}, err
+	}
+	return decimalValue(res), nil
+}
@@ -0,0 +1,109 @@
+package numeric
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// тесты для Calculate в режиме decimal
+func TestCalculate_Decimal(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          string
+		x, y        string
+		opts        Options
+		expectedRes string
+		expectedErr error
+	}{
+		{name: "Addition", op: "+", x: "0.1", y: "0.2", expectedRes: "0.3"},
+		{name: "Subtraction", op: "-", x: "1", y: "0.9", expectedRes: "0.1"},
+		{name: "Multiplication", op: "*", x: "1.10", y: "3", expectedRes: "3.3"},
+		{name: "Big numbers", op: "*", x: "123456789012345678901234567890", y: "10", expectedRes: "1234567890123456789012345678900"},
+		{name: "Division", op: "/", x: "1", y: "3", opts: Options{Scale: 5}, expectedRes: "0.33333"},
+		{name: "Exact division", op: "/", x: "1", y: "8", opts: Options{Scale: 20}, expectedRes: "0.125"},
+		{name: "Division by zero", op: "/", x: "1", y: "0", expectedErr: models.ErrDivisionByZero},
+		{name: "Integer division", op: "//", x: "-7", y: "2", expectedRes: "-4"},
+		{name: "Modulo", op: "%", x: "-7.5", y: "2", expectedRes: "0.5"},
+		{name: "Modulo by negative", op: "%", x: "7.5", y: "-2", expectedRes: "-0.5"},
+		{name: "Modulo by zero", op: "%", x: "1", y: "0", expectedErr: models.ErrModuloByZero},
+		{name: "Power", op: "^", x: "1.1", y: "3", expectedRes: "1.331"},
+		{name: "Negative power", op: "^", x: "3", y: "-1", opts: Options{Scale: 3}, expectedRes: "0.333"},
+		{name: "Fractional power", op: "^", x: "4", y: "0.5", expectedErr: models.ErrUnsupportedOp},
+		{name: "Power too large", op: "^", x: "2", y: "1001", expectedErr: models.ErrBadPower},
+		{name: "Result too large", op: "^", x: "1e1000", y: "1000", expectedErr: models.ErrBadPower},
+		{name: "Result denominator too large", op: "^", x: "1e-1000", y: "-1000", opts: Options{Scale: 5}, expectedErr: models.ErrBadPower},
+		{name: "Large result", op: "^", x: "1e100", y: "10", expectedRes: "1" + strings.Repeat("0", 1000)},
+		{name: "Zero to negative power", op: "^", x: "0", y: "-1", expectedErr: models.ErrBadPower},
+		{name: "Square root", op: "sqrt", x: "2", y: "0", opts: Options{Scale: 10}, expectedRes: "1.4142135624"},
+		{name: "Square root of negative", op: "sqrt", x: "-1", y: "0", expectedErr: models.ErrSqrtOfNegative},
+		{name: "Round", op: "round", x: "-2.5", y: "0", expectedRes: "-3"},
+		{name: "Min", op: "min", x: "0.30", y: "0.3", expectedRes: "0.3"},
+		{name: "Max", op: "max", x: "-1", y: "1e-3", expectedRes: "0.001"},
+		{name: "Unsupported function", op: "sin", x: "1", y: "0", expectedErr: models.ErrUnsupportedOp},
+		{name: "Bad number", op: "+", x: "1/2", y: "0", expectedErr: models.ErrBadNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Calculate(models.ModeDecimal, tt.op, Value{Exact: tt.x}, Value{Exact: tt.y}, tt.opts)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRes, res.Exact)
+		})
+	}
+}
+
+// тесты для способов округления результата деления
+func TestCalculate_DecimalRounding(t *testing.T) {
+	tests := []struct {
+		rounding string
+		// 0.125, -0.125, 0.121 до двух знаков
+		expected [3]string
+	}{
+		{rounding: models.RoundHalfEven, expected: [3]string{"0.12", "-0.12", "0.12"}},
+		{rounding: models.RoundHalfUp, expected: [3]string{"0.13", "-0.13", "0.12"}},
+		{rounding: models.RoundHalfDown, expected: [3]string{"0.12", "-0.12", "0.12"}},
+		{rounding: models.RoundUp, expected: [3]string{"0.13", "-0.13", "0.13"}},
+		{rounding: models.RoundDown, expected: [3]string{"0.12", "-0.12", "0.12"}},
+		{rounding: models.RoundCeiling, expected: [3]string{"0.13", "-0.12", "0.13"}},
+		{rounding: models.RoundFloor, expected: [3]string{"0.12", "-0.13", "0.12"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rounding, func(t *testing.T) {
+			opts := Options{Scale: 2, Rounding: tt.rounding}
+			for i, x := range []string{"0.125", "-0.125", "0.121"} {
+				res, err := Calculate(models.ModeDecimal, "/", Value{Exact: x}, Value{Exact: "1"}, opts)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected[i], res.Exact)
+			}
+		})
+	}
+}
+
+// тесты для Literal и FromFloat
+func TestLiteral(t *testing.T) {
+	v, err := Literal(models.ModeDecimal, ".5")
+	assert.NoError(t, err)
+	assert.Equal(t, Value{Float: 0.5, Exact: "0.5"}, v)
+
+	v, err = Literal(models.ModeDecimal, "1.50e2")
+	assert.NoError(t, err)
+	assert.Equal(t, "150", v.Exact)
+
+	_, err = Literal(models.ModeDecimal, "1e100000")
+	assert.ErrorIs(t, err, models.ErrBadNumber)
+
+	_, err = Literal("binary", "1")
+	assert.ErrorIs(t, err, models.ErrUnknownMode)
+
+	assert.Equal(t, "0.1", FromFloat(models.ModeDecimal, 0.1).Exact)
+	assert.Equal(t, "-0.1", Neg(models.ModeDecimal, FromFloat(models.ModeDecimal, 0.1)).Exact)
+	assert.Equal(t, Value{Float: 0.1}, FromFloat(models.ModeFloat, 0.1))
+}
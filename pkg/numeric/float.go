@@ -0,0 +1,85 @@
+package numeric
+
+import (
+	"math"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// вычисление операции в режиме float. бесконечность и NaN не записываются в JSON,
+// поэтому результат вне диапазона float64 - ошибка
+func calculateFloat(op string, x, y float64) (Value, error) {
+	res, err := floatOperation(op, x, y)
+	if err != nil {
+		return Value{}, err
+	}
+	if math.IsNaN(res.Float) || math.IsInf(res.Float, 0) {
+		return Value{}, models.ErrOverflow
+	}
+	return res, nil
+}
+
+func floatOperation(op string, x, y float64) (Value, error) {
+	switch op {
+	case "+":
+		return Value{Float: x + y}, nil
+	case "-":
+		return Value{Float: x - y}, nil
+	case "*":
+		return Value{Float: x * y}, nil
+	case "/":
+		if y == 0 {
+			return Value{}, models.ErrDivisionByZero
+		}
+		return Value{Float: x / y}, nil
+	case "^":
+		res := math.Pow(x, y)
+		if math.IsNaN(res) || math.IsInf(res, 0) {
+			return Value{}, models.ErrBadPower
+		}
+		return Value{Float: res}, nil
+	case "%":
+		if y == 0 {
+			return Value{}, models.ErrModuloByZero
+		}
+		return Value{Float: floorMod(x, y)}, nil
+	case "//":
+		if y == 0 {
+			return Value{}, models.ErrDivisionByZero
+		}
+		return Value{Float: math.Floor(x / y)}, nil
+	case "sqrt":
+		if x < 0 {
+			return Value{}, models.ErrSqrtOfNegative
+		}
+		return Value{Float: math.Sqrt(x)}, nil
+	case "sin":
+		return Value{Float: math.Sin(x)}, nil
+	case "cos":
+		return Value{Float: math.Cos(x)}, nil
+	case "log":
+		if x <= 0 {
+			return Value{}, models.ErrLogOfNonPositive
+		}
+		return Value{Float: math.Log(x)}, nil
+	case "abs":
+		return Value{Float: math.Abs(x)}, nil
+	case "round":
+		return Value{Float: math.Round(x)}, nil
+	case "min":
+		return Value{Float: min(x, y)}, nil
+	case "max":
+		return Value{Float: max(x, y)}, nil
+	}
+	return Value{}, models.ErrUnexpectedSymbol
+}
+
+// остаток от деления с округлением частного вниз: знак остатка совпадает со знаком
+// делителя, поэтому x == (x // y) * y + x % y
+func floorMod(x, y float64) float64 {
+	r := math.Mod(x, y)
+	if r != 0 && (r < 0) != (y < 0) {
+		r += y
+	}
+	return r
+}
@@ -0,0 +1,61 @@
+package numeric
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// тесты для Calculate в режиме float
+func TestCalculate_Float(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          string
+		x, y        float64
+		expectedRes float64
+		expectedErr error
+	}{
+		{name: "Addition", op: "+", x: 1, y: 2, expectedRes: 3},
+		{name: "Subtraction", op: "-", x: 1, y: 0.5, expectedRes: 0.5},
+		{name: "Multiplication", op: "*", x: 1.5, y: 4, expectedRes: 6},
+		{name: "Division", op: "/", x: 1, y: 4, expectedRes: 0.25},
+		{name: "Division by zero", op: "/", x: 1, y: 0, expectedErr: models.ErrDivisionByZero},
+		{name: "Power", op: "^", x: 2, y: 10, expectedRes: 1024},
+		{name: "Bad power", op: "^", x: -8, y: 0.5, expectedErr: models.ErrBadPower},
+		{name: "Modulo", op: "%", x: -7.5, y: 2, expectedRes: 0.5},
+		{name: "Modulo by negative", op: "%", x: 7.5, y: -2, expectedRes: -0.5},
+		{name: "Modulo by zero", op: "%", x: 1, y: 0, expectedErr: models.ErrModuloByZero},
+		{name: "Integer division", op: "//", x: -7, y: 2, expectedRes: -4},
+		{name: "Integer division by zero", op: "//", x: 1, y: 0, expectedErr: models.ErrDivisionByZero},
+		{name: "Square root", op: "sqrt", x: 16, expectedRes: 4},
+		{name: "Square root of negative", op: "sqrt", x: -1, expectedErr: models.ErrSqrtOfNegative},
+		{name: "Sine", op: "sin", x: math.Pi / 2, expectedRes: 1},
+		{name: "Cosine", op: "cos", x: 0, expectedRes: 1},
+		{name: "Logarithm", op: "log", x: math.E, expectedRes: 1},
+		{name: "Logarithm of zero", op: "log", x: 0, expectedErr: models.ErrLogOfNonPositive},
+		{name: "Abs", op: "abs", x: -2, expectedRes: 2},
+		{name: "Round", op: "round", x: 2.5, expectedRes: 3},
+		{name: "Min", op: "min", x: 1, y: -1, expectedRes: -1},
+		{name: "Max", op: "max", x: 1, y: -1, expectedRes: 1},
+		{name: "Unknown operation", op: "?", expectedErr: models.ErrUnexpectedSymbol},
+		{name: "Multiplication overflow", op: "*", x: 1e308, y: 10, expectedErr: models.ErrOverflow},
+		{name: "Addition overflow", op: "+", x: math.MaxFloat64, y: math.MaxFloat64, expectedErr: models.ErrOverflow},
+		{name: "Subtraction overflow", op: "-", x: -math.MaxFloat64, y: math.MaxFloat64, expectedErr: models.ErrOverflow},
+		{name: "Division overflow", op: "/", x: 1e308, y: 1e-10, expectedErr: models.ErrOverflow},
+		{name: "Integer division overflow", op: "//", x: 1e308, y: 1e-10, expectedErr: models.ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Calculate(models.ModeFloat, tt.op, Value{Float: tt.x}, Value{Float: tt.y}, Options{})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRes, res.Float)
+		})
+	}
+}
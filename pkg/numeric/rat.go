@@ -0,0 +1,113 @@
+package numeric
+
+import (
+	"math/big"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// вычисление операции над точными числами (режимы decimal и rational).
+// round - параметры округления неточных результатов (деления, корня, отрицательной
+// степени); nil - результат должен быть точным, а операции с иррациональным
+// результатом не поддерживаются
+func calculateRat(op string, x, y *big.Rat, round *Options) (*big.Rat, error) {
+	res := new(big.Rat)
+
+	switch op {
+	case "+":
+		res.Add(x, y)
+	case "-":
+		res.Sub(x, y)
+	case "*":
+		res.Mul(x, y)
+	case "/":
+		if y.Sign() == 0 {
+			return nil, models.ErrDivisionByZero
+		}
+		res.Quo(x, y)
+		if round != nil {
+			res = roundDecimal(res, round.Scale, round.Rounding)
+		}
+	case "//":
+		if y.Sign() == 0 {
+			return nil, models.ErrDivisionByZero
+		}
+		res.SetInt(floorRat(res.Quo(x, y)))
+	case "%":
+		// остаток со знаком делителя, согласованный с //: x - y*floor(x/y)
+		if y.Sign() == 0 {
+			return nil, models.ErrModuloByZero
+		}
+		q := new(big.Rat).SetInt(floorRat(new(big.Rat).Quo(x, y)))
+		res.Sub(x, q.Mul(q, y))
+	case "^":
+		if !y.IsInt() {
+			return nil, models.ErrUnsupportedOp
+		}
+		n := y.Num()
+		if n.CmpAbs(big.NewInt(maxExponent)) > 0 {
+			return nil, models.ErrBadPower
+		}
+		if x.Sign() == 0 && n.Sign() < 0 {
+			return nil, models.ErrBadPower
+		}
+		// размер a^n не больше n * размера a
+		abs := new(big.Int).Abs(n)
+		if max(x.Num().BitLen(), x.Denom().BitLen())*int(abs.Int64()) > maxResultBits {
+			return nil, models.ErrBadPower
+		}
+		num := new(big.Int).Exp(x.Num(), abs, nil)
+		den := new(big.Int).Exp(x.Denom(), abs, nil)
+		res.SetFrac(num, den)
+		if n.Sign() < 0 {
+			res.Inv(res)
+			if round != nil {
+				res = roundDecimal(res, round.Scale, round.Rounding)
+			}
+		}
+	case "sqrt":
+		if x.Sign() < 0 {
+			return nil, models.ErrSqrtOfNegative
+		}
+		if round == nil {
+			return exactSqrt(x)
+		}
+		// точность с запасом: scale десятичных знаков - около 3.33*scale бит
+		prec := uint(x.Num().BitLen()+x.Denom().BitLen()) + uint(round.Scale)*4 + 64
+		f := new(big.Float).SetPrec(prec).SetRat(x)
+		f.Sqrt(f)
+		f.Rat(res)
+		res = roundDecimal(res, round.Scale, round.Rounding)
+	case "abs":
+		res.Abs(x)
+	case "round":
+		// округление до целого, половина - от нуля, как у math.Round
+		res = roundDecimal(x, 0, models.RoundHalfUp)
+	case "min":
+		res.Set(x)
+		if y.Cmp(x) < 0 {
+			res.Set(y)
+		}
+	case "max":
+		res.Set(x)
+		if y.Cmp(x) > 0 {
+			res.Set(y)
+		}
+	case "sin", "cos", "log":
+		return nil, models.ErrUnsupportedOp
+	default:
+		return nil, models.ErrUnexpectedSymbol
+	}
+
+	return res, nil
+}
+
+// корень из дроби, числитель и знаменатель которой - точные квадраты: sqrt(4/9) = 2/3
+func exactSqrt(x *big.Rat) (*big.Rat, error) {
+	num := new(big.Int).Sqrt(x.Num())
+	den := new(big.Int).Sqrt(x.Denom())
+	if new(big.Int).Mul(num, num).Cmp(x.Num()) != 0 || new(big.Int).Mul(den, den).Cmp(x.Denom()) != 0 {
+		return nil, models.ErrUnsupportedOp
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
@@ -0,0 +1,52 @@
+package numeric
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// функция для разбора дроби: 1/3, -2/4 (сокращается до -1/2) или десятичной записи: 0.25 -> 1/4
+func parseRational(s string) (*big.Rat, error) {
+	num, den, isFrac := strings.Cut(s, "/")
+	if !isFrac {
+		return parseDecimal(s)
+	}
+
+	n, ok := new(big.Int).SetString(num, 10)
+	if !ok {
+		return nil, models.ErrBadNumber
+	}
+	d, ok := new(big.Int).SetString(den, 10)
+	if !ok || d.Sign() <= 0 {
+		return nil, models.ErrBadNumber
+	}
+	return new(big.Rat).SetFrac(n, d), nil
+}
+
+// точное значение в виде несократимой дроби и его приближение
+func rationalValue(r *big.Rat) Value {
+	f, _ := r.Float64()
+	return Value{Float: f, Exact: r.RatString()}
+}
+
+// вычисление операции над дробями. все результаты точные, поэтому операции
+// с иррациональным результатом (sin, cos, log, дробные степени, корень из числа,
+// не являющегося квадратом дроби) не поддерживаются
+func calculateRational(op string, xv, yv Value) (Value, error) {
+	x, err := parseRational(xv.Exact)
+	if err != nil {
+		return Value{}, err
+	}
+	y, err := parseRational(yv.Exact)
+	if err != nil {
+		return Value{}, err
+	}
+
+	res, err := calculateRat(op, x, y, nil)
+	if err != nil {
+		return Value{}, err
+	}
+	return rationalValue(res), nil
+}
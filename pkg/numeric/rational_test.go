@@ -0,0 +1,63 @@
+package numeric
+
+import (
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// тесты для Calculate в режиме rational
+func TestCalculate_Rational(t *testing.T) {
+	tests := []struct {
+		name        string
+		op          string
+		x, y        string
+		expectedRes string
+		expectedErr error
+	}{
+		{name: "Addition", op: "+", x: "1/3", y: "1/6", expectedRes: "1/2"},
+		{name: "Integer result", op: "*", x: "2/3", y: "3/2", expectedRes: "1"},
+		{name: "Decimal operands", op: "+", x: "0.1", y: "0.2", expectedRes: "3/10"},
+		{name: "Division", op: "/", x: "1", y: "3", expectedRes: "1/3"},
+		{name: "Division by zero", op: "/", x: "1", y: "0", expectedErr: models.ErrDivisionByZero},
+		{name: "Integer division", op: "//", x: "-7/2", y: "1", expectedRes: "-4"},
+		{name: "Modulo", op: "%", x: "7/2", y: "1", expectedRes: "1/2"},
+		{name: "Negative modulo", op: "%", x: "-7/2", y: "1", expectedRes: "1/2"},
+		{name: "Negative power", op: "^", x: "2/3", y: "-2", expectedRes: "9/4"},
+		{name: "Result too large", op: "^", x: "1/100000000000000000000000", y: "-1000", expectedErr: models.ErrBadPower},
+		{name: "Fractional power", op: "^", x: "4", y: "1/2", expectedErr: models.ErrUnsupportedOp},
+		{name: "Exact square root", op: "sqrt", x: "4/9", y: "0", expectedRes: "2/3"},
+		{name: "Irrational square root", op: "sqrt", x: "2", y: "0", expectedErr: models.ErrUnsupportedOp},
+		{name: "Round", op: "round", x: "5/2", y: "0", expectedRes: "3"},
+		{name: "Min", op: "min", x: "1/3", y: "0.3", expectedRes: "3/10"},
+		{name: "Unsupported function", op: "log", x: "1", y: "0", expectedErr: models.ErrUnsupportedOp},
+		{name: "Zero denominator", op: "+", x: "1/0", y: "0", expectedErr: models.ErrBadNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Calculate(models.ModeRational, tt.op, Value{Exact: tt.x}, Value{Exact: tt.y}, Options{})
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedRes, res.Exact)
+		})
+	}
+}
+
+// тесты для Fraction и Approximate
+func TestFraction(t *testing.T) {
+	v, err := Parse(models.ModeRational, "-2/6")
+	assert.NoError(t, err)
+	assert.Equal(t, "-1/3", v.Exact)
+
+	assert.Equal(t, &models.Fraction{Numerator: "-1", Denominator: "3"}, Fraction(models.ModeRational, v))
+	assert.Equal(t, "-0.333", Approximate(models.ModeRational, v, Options{Scale: 3, Rounding: models.RoundHalfEven}))
+	assert.Equal(t, "1/3", Neg(models.ModeRational, v).Exact)
+
+	assert.Nil(t, Fraction(models.ModeDecimal, Value{Exact: "0.5"}))
+	assert.Empty(t, Approximate(models.ModeDecimal, Value{Exact: "0.5"}, Options{}))
+}
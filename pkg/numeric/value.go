@@ -0,0 +1,166 @@
+package numeric
+
+import (
+	"strconv"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+)
+
+// число в одном из режимов вычисления. в режиме float используется Float,
+// в остальных режимах Exact - точная запись числа, а Float - ее приближение.
+// в режиме complex Float и Imag - действительная и мнимая части
+type Value struct {
+	Float float64 `json:"float"`
+	Imag  float64 `json:"imag,omitempty"`
+	Exact string  `json:"exact,omitempty"`
+}
+
+// запись числа: точная, если она есть
+func (v Value) String() string {
+	if v.Exact != "" {
+		return v.Exact
+	}
+	return strconv.FormatFloat(v.Float, 'g', -1, 64)
+}
+
+// параметры точных вычислений: число знаков после запятой и способ округления
+// результата деления (см. models.Round*)
+type Options struct {
+	Scale    int
+	Rounding string
+}
+
+// функция для проверки режима вычисления
+func ValidMode(mode string) bool {
+	switch mode {
+	case models.ModeFloat, models.ModeDecimal, models.ModeRational, models.ModeComplex:
+		return true
+	}
+	return false
+}
+
+// функция для проверки способа округления
+func ValidRounding(rounding string) bool {
+	switch rounding {
+	case models.RoundHalfEven, models.RoundHalfUp, models.RoundHalfDown,
+		models.RoundUp, models.RoundDown, models.RoundCeiling, models.RoundFloor:
+		return true
+	}
+	return false
+}
+
+// число из литерала выражения. в точных режимах литерал не округляется: 0.1 - ровно 0.1
+func Literal(mode string, text string) (Value, error) {
+	if mode == models.ModeFloat {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return Value{}, models.ErrBadNumber
+		}
+		return Value{Float: f}, nil
+	}
+	return Parse(mode, text)
+}
+
+// число из значения переменной, константы или результата другого выражения.
+// в точных режимах берется кратчайшая десятичная запись float64: 0.1 -> "0.1"
+func FromFloat(mode string, f float64) Value {
+	switch mode {
+	case models.ModeFloat:
+		return Value{Float: f}
+	case models.ModeComplex:
+		return Complex(f, 0)
+	}
+	v, _ := Parse(mode, strconv.FormatFloat(f, 'f', -1, 64))
+	return v
+}
+
+// число по точной записи
+func Parse(mode string, text string) (Value, error) {
+	switch mode {
+	case models.ModeFloat:
+		return Literal(mode, text)
+	case models.ModeDecimal:
+		r, err := parseDecimal(text)
+		if err != nil {
+			return Value{}, err
+		}
+		return decimalValue(r), nil
+	case models.ModeRational:
+		r, err := parseRational(text)
+		if err != nil {
+			return Value{}, err
+		}
+		return rationalValue(r), nil
+	case models.ModeComplex:
+		c, err := parseComplex(text)
+		if err != nil {
+			return Value{}, err
+		}
+		return complexValue(c), nil
+	}
+	return Value{}, models.ErrUnknownMode
+}
+
+// число с обратным знаком
+func Neg(mode string, v Value) Value {
+	switch mode {
+	case models.ModeDecimal:
+		r, err := parseDecimal(v.Exact)
+		if err != nil {
+			return Value{Float: -v.Float}
+		}
+		return decimalValue(r.Neg(r))
+	case models.ModeRational:
+		r, err := parseRational(v.Exact)
+		if err != nil {
+			return Value{Float: -v.Float}
+		}
+		return rationalValue(r.Neg(r))
+	case models.ModeComplex:
+		// вычитание из нуля вместо смены знака не дает -0: иначе sqrt(-4) попал бы
+		// на другой берег разреза и дал -2i
+		return Complex(0-v.Float, 0-v.Imag)
+	}
+	return Value{Float: -v.Float}
+}
+
+// вычисление операции в режиме mode. так операции вычисляет агент (Worker),
+// а оркестратор - операции, которые он вычисляет сам
+func Calculate(mode string, op string, x, y Value, opts Options) (Value, error) {
+	switch mode {
+	case models.ModeFloat:
+		return calculateFloat(op, x.Float, y.Float)
+	case models.ModeDecimal:
+		return calculateDecimal(op, x, y, opts)
+	case models.ModeRational:
+		return calculateRational(op, x, y)
+	case models.ModeComplex:
+		return calculateComplex(op, x, y)
+	}
+	return Value{}, models.ErrUnknownMode
+}
+
+// числитель и знаменатель результата в режиме rational (nil в остальных режимах)
+func Fraction(mode string, v Value) *models.Fraction {
+	if mode != models.ModeRational {
+		return nil
+	}
+	r, err := parseRational(v.Exact)
+	if err != nil {
+		return nil
+	}
+	return &models.Fraction{Numerator: r.Num().String(), Denominator: r.Denom().String()}
+}
+
+// десятичное приближение дроби до opts.Scale знаков после запятой способом opts.Rounding
+// (пусто в остальных режимах)
+func Approximate(mode string, v Value, opts Options) string {
+	if mode != models.ModeRational {
+		return ""
+	}
+	r, err := parseRational(v.Exact)
+	if err != nil {
+		return ""
+	}
+	return formatDecimal(roundDecimal(r, opts.Scale, opts.Rounding))
+}
@@ -0,0 +1,37 @@
+package numeric
+
+import (
+	"testing"
+
+	"github.com/ArtemiySps/calc_go_final/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// во всех режимах // и % согласованы: x == (x // y) * y + x % y, в том числе
+// для отрицательных аргументов
+func TestCalculate_ModuloIdentity(t *testing.T) {
+	modes := []string{models.ModeFloat, models.ModeDecimal, models.ModeRational, models.ModeComplex}
+	operands := [][2]string{{"7", "3"}, {"-7", "3"}, {"7", "-3"}, {"-7", "-3"}, {"-7.5", "2"}, {"7.5", "-2"}}
+
+	for _, mode := range modes {
+		for _, args := range operands {
+			x, err := Literal(mode, args[0])
+			assert.NoError(t, err)
+			y, err := Literal(mode, args[1])
+			assert.NoError(t, err)
+
+			q, err := Calculate(mode, "//", x, y, Options{})
+			assert.NoError(t, err)
+			r, err := Calculate(mode, "%", x, y, Options{})
+			assert.NoError(t, err)
+			// остаток имеет знак делителя
+			assert.False(t, r.Float*y.Float < 0, "%s: %s %% %s = %s", mode, args[0], args[1], r)
+
+			qy, err := Calculate(mode, "*", q, y, Options{})
+			assert.NoError(t, err)
+			res, err := Calculate(mode, "+", qy, r, Options{})
+			assert.NoError(t, err)
+			assert.Equal(t, x.String(), res.String(), "%s: %s, %s", mode, args[0], args[1])
+		}
+	}
+}
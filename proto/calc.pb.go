@@ -21,11 +21,30 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// числа передаются в double-полях. float-поля оставлены для совместимости со старыми
+// агентами и оркестраторами: отправитель заполняет оба поля, получатель берет
+// double-поле, если оно задано (см. compat.go).
+// в точных режимах (mode, кроме float) числа передаются точной записью в *_exact-полях
+// (0.1 в режиме decimal, 1/3 в режиме rational), а числовые поля содержат приближения.
+// в режиме complex действительные части передаются в double-полях, мнимые - в *_imag-полях.
+// scale и rounding - число знаков после запятой и способ округления результата деления
+// в режиме decimal. timeout_ms - время на операцию с момента, когда воркер агента начал
+// ее вычислять (время в очереди агента не учитывается), 0 - без ограничения
 type TaskRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Arg1          float32                `protobuf:"fixed32,1,opt,name=arg1,proto3" json:"arg1,omitempty"`
 	Arg2          float32                `protobuf:"fixed32,2,opt,name=arg2,proto3" json:"arg2,omitempty"`
 	Opr           string                 `protobuf:"bytes,3,opt,name=opr,proto3" json:"opr,omitempty"`
+	Arg1Double    *float64               `protobuf:"fixed64,4,opt,name=arg1_double,json=arg1Double,proto3,oneof" json:"arg1_double,omitempty"`
+	Arg2Double    *float64               `protobuf:"fixed64,5,opt,name=arg2_double,json=arg2Double,proto3,oneof" json:"arg2_double,omitempty"`
+	Mode          string                 `protobuf:"bytes,6,opt,name=mode,proto3" json:"mode,omitempty"`
+	Arg1Exact     string                 `protobuf:"bytes,7,opt,name=arg1_exact,json=arg1Exact,proto3" json:"arg1_exact,omitempty"`
+	Arg2Exact     string                 `protobuf:"bytes,8,opt,name=arg2_exact,json=arg2Exact,proto3" json:"arg2_exact,omitempty"`
+	Scale         int32                  `protobuf:"varint,9,opt,name=scale,proto3" json:"scale,omitempty"`
+	Rounding      string                 `protobuf:"bytes,10,opt,name=rounding,proto3" json:"rounding,omitempty"`
+	Arg1Imag      *float64               `protobuf:"fixed64,11,opt,name=arg1_imag,json=arg1Imag,proto3,oneof" json:"arg1_imag,omitempty"`
+	Arg2Imag      *float64               `protobuf:"fixed64,12,opt,name=arg2_imag,json=arg2Imag,proto3,oneof" json:"arg2_imag,omitempty"`
+	TimeoutMs     int32                  `protobuf:"varint,13,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,9 +100,82 @@ func (x *TaskRequest) GetOpr() string {
 	return ""
 }
 
+func (x *TaskRequest) GetArg1Double() float64 {
+	if x != nil && x.Arg1Double != nil {
+		return *x.Arg1Double
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetArg2Double() float64 {
+	if x != nil && x.Arg2Double != nil {
+		return *x.Arg2Double
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetArg1Exact() string {
+	if x != nil {
+		return x.Arg1Exact
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetArg2Exact() string {
+	if x != nil {
+		return x.Arg2Exact
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetScale() int32 {
+	if x != nil {
+		return x.Scale
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetRounding() string {
+	if x != nil {
+		return x.Rounding
+	}
+	return ""
+}
+
+func (x *TaskRequest) GetArg1Imag() float64 {
+	if x != nil && x.Arg1Imag != nil {
+		return *x.Arg1Imag
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetArg2Imag() float64 {
+	if x != nil && x.Arg2Imag != nil {
+		return *x.Arg2Imag
+	}
+	return 0
+}
+
+func (x *TaskRequest) GetTimeoutMs() int32 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
 type ResResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Res           float32                `protobuf:"fixed32,1,opt,name=res,proto3" json:"res,omitempty"`
+	ResDouble     *float64               `protobuf:"fixed64,2,opt,name=res_double,json=resDouble,proto3,oneof" json:"res_double,omitempty"`
+	ResExact      string                 `protobuf:"bytes,3,opt,name=res_exact,json=resExact,proto3" json:"res_exact,omitempty"`
+	ResImag       *float64               `protobuf:"fixed64,4,opt,name=res_imag,json=resImag,proto3,oneof" json:"res_imag,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -125,19 +217,684 @@ func (x *ResResponse) GetRes() float32 {
 	return 0
 }
 
+func (x *ResResponse) GetResDouble() float64 {
+	if x != nil && x.ResDouble != nil {
+		return *x.ResDouble
+	}
+	return 0
+}
+
+func (x *ResResponse) GetResExact() string {
+	if x != nil {
+		return x.ResExact
+	}
+	return ""
+}
+
+func (x *ResResponse) GetResImag() float64 {
+	if x != nil && x.ResImag != nil {
+		return *x.ResImag
+	}
+	return 0
+}
+
+// запрос агента на получение задачи. agent_id - ID, выданный при регистрации
+type GetTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTaskRequest) Reset() {
+	*x = GetTaskRequest{}
+	mi := &file_proto_calc_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTaskRequest) ProtoMessage() {}
+
+func (x *GetTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTaskRequest.ProtoReflect.Descriptor instead.
+func (*GetTaskRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetTaskRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+// задача из очереди оркестратора
+type Task struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Arg1          float32                `protobuf:"fixed32,2,opt,name=arg1,proto3" json:"arg1,omitempty"`
+	Arg2          float32                `protobuf:"fixed32,3,opt,name=arg2,proto3" json:"arg2,omitempty"`
+	Opr           string                 `protobuf:"bytes,4,opt,name=opr,proto3" json:"opr,omitempty"`
+	Arg1Double    *float64               `protobuf:"fixed64,5,opt,name=arg1_double,json=arg1Double,proto3,oneof" json:"arg1_double,omitempty"`
+	Arg2Double    *float64               `protobuf:"fixed64,6,opt,name=arg2_double,json=arg2Double,proto3,oneof" json:"arg2_double,omitempty"`
+	Mode          string                 `protobuf:"bytes,7,opt,name=mode,proto3" json:"mode,omitempty"`
+	Arg1Exact     string                 `protobuf:"bytes,8,opt,name=arg1_exact,json=arg1Exact,proto3" json:"arg1_exact,omitempty"`
+	Arg2Exact     string                 `protobuf:"bytes,9,opt,name=arg2_exact,json=arg2Exact,proto3" json:"arg2_exact,omitempty"`
+	Scale         int32                  `protobuf:"varint,10,opt,name=scale,proto3" json:"scale,omitempty"`
+	Rounding      string                 `protobuf:"bytes,11,opt,name=rounding,proto3" json:"rounding,omitempty"`
+	Arg1Imag      *float64               `protobuf:"fixed64,12,opt,name=arg1_imag,json=arg1Imag,proto3,oneof" json:"arg1_imag,omitempty"`
+	Arg2Imag      *float64               `protobuf:"fixed64,13,opt,name=arg2_imag,json=arg2Imag,proto3,oneof" json:"arg2_imag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_proto_calc_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Task) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Task) GetArg1() float32 {
+	if x != nil {
+		return x.Arg1
+	}
+	return 0
+}
+
+func (x *Task) GetArg2() float32 {
+	if x != nil {
+		return x.Arg2
+	}
+	return 0
+}
+
+func (x *Task) GetOpr() string {
+	if x != nil {
+		return x.Opr
+	}
+	return ""
+}
+
+func (x *Task) GetArg1Double() float64 {
+	if x != nil && x.Arg1Double != nil {
+		return *x.Arg1Double
+	}
+	return 0
+}
+
+func (x *Task) GetArg2Double() float64 {
+	if x != nil && x.Arg2Double != nil {
+		return *x.Arg2Double
+	}
+	return 0
+}
+
+func (x *Task) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Task) GetArg1Exact() string {
+	if x != nil {
+		return x.Arg1Exact
+	}
+	return ""
+}
+
+func (x *Task) GetArg2Exact() string {
+	if x != nil {
+		return x.Arg2Exact
+	}
+	return ""
+}
+
+func (x *Task) GetScale() int32 {
+	if x != nil {
+		return x.Scale
+	}
+	return 0
+}
+
+func (x *Task) GetRounding() string {
+	if x != nil {
+		return x.Rounding
+	}
+	return ""
+}
+
+func (x *Task) GetArg1Imag() float64 {
+	if x != nil && x.Arg1Imag != nil {
+		return *x.Arg1Imag
+	}
+	return 0
+}
+
+func (x *Task) GetArg2Imag() float64 {
+	if x != nil && x.Arg2Imag != nil {
+		return *x.Arg2Imag
+	}
+	return 0
+}
+
+// результат задачи от агента. error - текст ошибки вычисления (пусто, если ошибки нет)
+type TaskResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Res           float32                `protobuf:"fixed32,2,opt,name=res,proto3" json:"res,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	AgentId       string                 `protobuf:"bytes,4,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	ResDouble     *float64               `protobuf:"fixed64,5,opt,name=res_double,json=resDouble,proto3,oneof" json:"res_double,omitempty"`
+	ResExact      string                 `protobuf:"bytes,6,opt,name=res_exact,json=resExact,proto3" json:"res_exact,omitempty"`
+	ResImag       *float64               `protobuf:"fixed64,7,opt,name=res_imag,json=resImag,proto3,oneof" json:"res_imag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskResult) Reset() {
+	*x = TaskResult{}
+	mi := &file_proto_calc_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskResult) ProtoMessage() {}
+
+func (x *TaskResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskResult.ProtoReflect.Descriptor instead.
+func (*TaskResult) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *TaskResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TaskResult) GetRes() float32 {
+	if x != nil {
+		return x.Res
+	}
+	return 0
+}
+
+func (x *TaskResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *TaskResult) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *TaskResult) GetResDouble() float64 {
+	if x != nil && x.ResDouble != nil {
+		return *x.ResDouble
+	}
+	return 0
+}
+
+func (x *TaskResult) GetResExact() string {
+	if x != nil {
+		return x.ResExact
+	}
+	return ""
+}
+
+func (x *TaskResult) GetResImag() float64 {
+	if x != nil && x.ResImag != nil {
+		return *x.ResImag
+	}
+	return 0
+}
+
+type SubmitResultResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitResultResponse) Reset() {
+	*x = SubmitResultResponse{}
+	mi := &file_proto_calc_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitResultResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitResultResponse) ProtoMessage() {}
+
+func (x *SubmitResultResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitResultResponse.ProtoReflect.Descriptor instead.
+func (*SubmitResultResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{5}
+}
+
+// регистрация агента при запуске. address - адрес gRPC сервера агента
+// (пусто, если агент сам забирает задачи)
+type RegisterRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Address        string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	ComputingPower int32                  `protobuf:"varint,2,opt,name=computing_power,json=computingPower,proto3" json:"computing_power,omitempty"`
+	Operations     []string               `protobuf:"bytes,3,rep,name=operations,proto3" json:"operations,omitempty"`
+	Version        string                 `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
+	mi := &file_proto_calc_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRequest) ProtoMessage() {}
+
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RegisterRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetComputingPower() int32 {
+	if x != nil {
+		return x.ComputingPower
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+// heartbeat_interval_ms - как часто агент должен отправлять Heartbeat
+type RegisterResponse struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	AgentId             string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	HeartbeatIntervalMs int32                  `protobuf:"varint,2,opt,name=heartbeat_interval_ms,json=heartbeatIntervalMs,proto3" json:"heartbeat_interval_ms,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *RegisterResponse) Reset() {
+	*x = RegisterResponse{}
+	mi := &file_proto_calc_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterResponse) ProtoMessage() {}
+
+func (x *RegisterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterResponse.ProtoReflect.Descriptor instead.
+func (*RegisterResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RegisterResponse) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *RegisterResponse) GetHeartbeatIntervalMs() int32 {
+	if x != nil {
+		return x.HeartbeatIntervalMs
+	}
+	return 0
+}
+
+// вместе с Heartbeat агент сообщает загрузку: число воркеров и занятых воркеров,
+// число операций в очереди и ее размер
+type HeartbeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgentId       string                 `protobuf:"bytes,1,opt,name=agent_id,json=agentId,proto3" json:"agent_id,omitempty"`
+	Workers       int32                  `protobuf:"varint,2,opt,name=workers,proto3" json:"workers,omitempty"`
+	BusyWorkers   int32                  `protobuf:"varint,3,opt,name=busy_workers,json=busyWorkers,proto3" json:"busy_workers,omitempty"`
+	QueueDepth    int32                  `protobuf:"varint,4,opt,name=queue_depth,json=queueDepth,proto3" json:"queue_depth,omitempty"`
+	QueueSize     int32                  `protobuf:"varint,5,opt,name=queue_size,json=queueSize,proto3" json:"queue_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_proto_calc_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HeartbeatRequest) GetAgentId() string {
+	if x != nil {
+		return x.AgentId
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetBusyWorkers() int32 {
+	if x != nil {
+		return x.BusyWorkers
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetQueueDepth() int32 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetQueueSize() int32 {
+	if x != nil {
+		return x.QueueSize
+	}
+	return 0
+}
+
+type HeartbeatResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_proto_calc_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_calc_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_calc_proto_rawDescGZIP(), []int{9}
+}
+
 var File_proto_calc_proto protoreflect.FileDescriptor
 
 const file_proto_calc_proto_rawDesc = "" +
 	"\n" +
-	"\x10proto/calc.proto\x12\x04calc\"G\n" +
+	"\x10proto/calc.proto\x12\x04calc\"\xb6\x03\n" +
 	"\vTaskRequest\x12\x12\n" +
 	"\x04arg1\x18\x01 \x01(\x02R\x04arg1\x12\x12\n" +
 	"\x04arg2\x18\x02 \x01(\x02R\x04arg2\x12\x10\n" +
-	"\x03opr\x18\x03 \x01(\tR\x03opr\"\x1f\n" +
+	"\x03opr\x18\x03 \x01(\tR\x03opr\x12$\n" +
+	"\varg1_double\x18\x04 \x01(\x01H\x00R\n" +
+	"arg1Double\x88\x01\x01\x12$\n" +
+	"\varg2_double\x18\x05 \x01(\x01H\x01R\n" +
+	"arg2Double\x88\x01\x01\x12\x12\n" +
+	"\x04mode\x18\x06 \x01(\tR\x04mode\x12\x1d\n" +
+	"\n" +
+	"arg1_exact\x18\a \x01(\tR\targ1Exact\x12\x1d\n" +
+	"\n" +
+	"arg2_exact\x18\b \x01(\tR\targ2Exact\x12\x14\n" +
+	"\x05scale\x18\t \x01(\x05R\x05scale\x12\x1a\n" +
+	"\brounding\x18\n" +
+	" \x01(\tR\brounding\x12 \n" +
+	"\targ1_imag\x18\v \x01(\x01H\x02R\barg1Imag\x88\x01\x01\x12 \n" +
+	"\targ2_imag\x18\f \x01(\x01H\x03R\barg2Imag\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\r \x01(\x05R\ttimeoutMsB\x0e\n" +
+	"\f_arg1_doubleB\x0e\n" +
+	"\f_arg2_doubleB\f\n" +
+	"\n" +
+	"_arg1_imagB\f\n" +
+	"\n" +
+	"_arg2_imag\"\x9c\x01\n" +
 	"\vResResponse\x12\x10\n" +
-	"\x03res\x18\x01 \x01(\x02R\x03res2B\n" +
+	"\x03res\x18\x01 \x01(\x02R\x03res\x12\"\n" +
+	"\n" +
+	"res_double\x18\x02 \x01(\x01H\x00R\tresDouble\x88\x01\x01\x12\x1b\n" +
+	"\tres_exact\x18\x03 \x01(\tR\bresExact\x12\x1e\n" +
+	"\bres_imag\x18\x04 \x01(\x01H\x01R\aresImag\x88\x01\x01B\r\n" +
+	"\v_res_doubleB\v\n" +
+	"\t_res_imag\"+\n" +
+	"\x0eGetTaskRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\"\xa0\x03\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04arg1\x18\x02 \x01(\x02R\x04arg1\x12\x12\n" +
+	"\x04arg2\x18\x03 \x01(\x02R\x04arg2\x12\x10\n" +
+	"\x03opr\x18\x04 \x01(\tR\x03opr\x12$\n" +
+	"\varg1_double\x18\x05 \x01(\x01H\x00R\n" +
+	"arg1Double\x88\x01\x01\x12$\n" +
+	"\varg2_double\x18\x06 \x01(\x01H\x01R\n" +
+	"arg2Double\x88\x01\x01\x12\x12\n" +
+	"\x04mode\x18\a \x01(\tR\x04mode\x12\x1d\n" +
+	"\n" +
+	"arg1_exact\x18\b \x01(\tR\targ1Exact\x12\x1d\n" +
+	"\n" +
+	"arg2_exact\x18\t \x01(\tR\targ2Exact\x12\x14\n" +
+	"\x05scale\x18\n" +
+	" \x01(\x05R\x05scale\x12\x1a\n" +
+	"\brounding\x18\v \x01(\tR\brounding\x12 \n" +
+	"\targ1_imag\x18\f \x01(\x01H\x02R\barg1Imag\x88\x01\x01\x12 \n" +
+	"\targ2_imag\x18\r \x01(\x01H\x03R\barg2Imag\x88\x01\x01B\x0e\n" +
+	"\f_arg1_doubleB\x0e\n" +
+	"\f_arg2_doubleB\f\n" +
+	"\n" +
+	"_arg1_imagB\f\n" +
+	"\n" +
+	"_arg2_imag\"\xdc\x01\n" +
+	"\n" +
+	"TaskResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03res\x18\x02 \x01(\x02R\x03res\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x19\n" +
+	"\bagent_id\x18\x04 \x01(\tR\aagentId\x12\"\n" +
+	"\n" +
+	"res_double\x18\x05 \x01(\x01H\x00R\tresDouble\x88\x01\x01\x12\x1b\n" +
+	"\tres_exact\x18\x06 \x01(\tR\bresExact\x12\x1e\n" +
+	"\bres_imag\x18\a \x01(\x01H\x01R\aresImag\x88\x01\x01B\r\n" +
+	"\v_res_doubleB\v\n" +
+	"\t_res_imag\"\x16\n" +
+	"\x14SubmitResultResponse\"\x8e\x01\n" +
+	"\x0fRegisterRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12'\n" +
+	"\x0fcomputing_power\x18\x02 \x01(\x05R\x0ecomputingPower\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x03 \x03(\tR\n" +
+	"operations\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\"a\n" +
+	"\x10RegisterResponse\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x122\n" +
+	"\x15heartbeat_interval_ms\x18\x02 \x01(\x05R\x13heartbeatIntervalMs\"\xaa\x01\n" +
+	"\x10HeartbeatRequest\x12\x19\n" +
+	"\bagent_id\x18\x01 \x01(\tR\aagentId\x12\x18\n" +
+	"\aworkers\x18\x02 \x01(\x05R\aworkers\x12!\n" +
+	"\fbusy_workers\x18\x03 \x01(\x05R\vbusyWorkers\x12\x1f\n" +
+	"\vqueue_depth\x18\x04 \x01(\x05R\n" +
+	"queueDepth\x12\x1d\n" +
+	"\n" +
+	"queue_size\x18\x05 \x01(\x05R\tqueueSize\"\x13\n" +
+	"\x11HeartbeatResponse2\xa6\x02\n" +
 	"\vCalcService\x123\n" +
-	"\vCalculation\x12\x11.calc.TaskRequest\x1a\x11.calc.ResResponseB+Z)github.com/ArtemiySps/calc_go_final/protob\x06proto3"
+	"\vCalculation\x12\x11.calc.TaskRequest\x1a\x11.calc.ResResponse\x12+\n" +
+	"\aGetTask\x12\x14.calc.GetTaskRequest\x1a\n" +
+	".calc.Task\x12<\n" +
+	"\fSubmitResult\x12\x10.calc.TaskResult\x1a\x1a.calc.SubmitResultResponse\x129\n" +
+	"\bRegister\x12\x15.calc.RegisterRequest\x1a\x16.calc.RegisterResponse\x12<\n" +
+	"\tHeartbeat\x12\x16.calc.HeartbeatRequest\x1a\x17.calc.HeartbeatResponseB+Z)github.com/ArtemiySps/calc_go_final/protob\x06proto3"
 
 var (
 	file_proto_calc_proto_rawDescOnce sync.Once
@@ -151,16 +908,32 @@ func file_proto_calc_proto_rawDescGZIP() []byte {
 	return file_proto_calc_proto_rawDescData
 }
 
-var file_proto_calc_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_calc_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_proto_calc_proto_goTypes = []any{
-	(*TaskRequest)(nil), // 0: calc.TaskRequest
-	(*ResResponse)(nil), // 1: calc.ResResponse
+	(*TaskRequest)(nil),          // 0: calc.TaskRequest
+	(*ResResponse)(nil),          // 1: calc.ResResponse
+	(*GetTaskRequest)(nil),       // 2: calc.GetTaskRequest
+	(*Task)(nil),                 // 3: calc.Task
+	(*TaskResult)(nil),           // 4: calc.TaskResult
+	(*SubmitResultResponse)(nil), // 5: calc.SubmitResultResponse
+	(*RegisterRequest)(nil),      // 6: calc.RegisterRequest
+	(*RegisterResponse)(nil),     // 7: calc.RegisterResponse
+	(*HeartbeatRequest)(nil),     // 8: calc.HeartbeatRequest
+	(*HeartbeatResponse)(nil),    // 9: calc.HeartbeatResponse
 }
 var file_proto_calc_proto_depIdxs = []int32{
 	0, // 0: calc.CalcService.Calculation:input_type -> calc.TaskRequest
-	1, // 1: calc.CalcService.Calculation:output_type -> calc.ResResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
+	2, // 1: calc.CalcService.GetTask:input_type -> calc.GetTaskRequest
+	4, // 2: calc.CalcService.SubmitResult:input_type -> calc.TaskResult
+	6, // 3: calc.CalcService.Register:input_type -> calc.RegisterRequest
+	8, // 4: calc.CalcService.Heartbeat:input_type -> calc.HeartbeatRequest
+	1, // 5: calc.CalcService.Calculation:output_type -> calc.ResResponse
+	3, // 6: calc.CalcService.GetTask:output_type -> calc.Task
+	5, // 7: calc.CalcService.SubmitResult:output_type -> calc.SubmitResultResponse
+	7, // 8: calc.CalcService.Register:output_type -> calc.RegisterResponse
+	9, // 9: calc.CalcService.Heartbeat:output_type -> calc.HeartbeatResponse
+	5, // [5:10] is the sub-list for method output_type
+	0, // [0:5] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -171,13 +944,17 @@ func file_proto_calc_proto_init() {
 	if File_proto_calc_proto != nil {
 		return
 	}
+	file_proto_calc_proto_msgTypes[0].OneofWrappers = []any{}
+	file_proto_calc_proto_msgTypes[1].OneofWrappers = []any{}
+	file_proto_calc_proto_msgTypes[3].OneofWrappers = []any{}
+	file_proto_calc_proto_msgTypes[4].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_calc_proto_rawDesc), len(file_proto_calc_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
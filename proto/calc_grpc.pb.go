@@ -19,14 +19,29 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	CalcService_Calculation_FullMethodName = "/calc.CalcService/Calculation"
+	CalcService_Calculation_FullMethodName  = "/calc.CalcService/Calculation"
+	CalcService_GetTask_FullMethodName      = "/calc.CalcService/GetTask"
+	CalcService_SubmitResult_FullMethodName = "/calc.CalcService/SubmitResult"
+	CalcService_Register_FullMethodName     = "/calc.CalcService/Register"
+	CalcService_Heartbeat_FullMethodName    = "/calc.CalcService/Heartbeat"
 )
 
 // CalcServiceClient is the client API for CalcService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CalcServiceClient interface {
+	// вычисление одной операции агентом (оркестратор - клиент). если очередь
+	// агента заполнена, Calculation возвращает код RESOURCE_EXHAUSTED
 	Calculation(ctx context.Context, in *TaskRequest, opts ...grpc.CallOption) (*ResResponse, error)
+	// агенты сами забирают задачи из очереди оркестратора и возвращают результаты.
+	// если задач нет, GetTask возвращает код NOT_FOUND
+	GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error)
+	SubmitResult(ctx context.Context, in *TaskResult, opts ...grpc.CallOption) (*SubmitResultResponse, error)
+	// агент сообщает о себе при запуске и затем периодически подтверждает, что жив.
+	// если оркестратор не знает агента (например, он был признан потерянным),
+	// Heartbeat возвращает код NOT_FOUND, и агент регистрируется заново
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error)
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
 }
 
 type calcServiceClient struct {
@@ -47,11 +62,62 @@ func (c *calcServiceClient) Calculation(ctx context.Context, in *TaskRequest, op
 	return out, nil
 }
 
+func (c *calcServiceClient) GetTask(ctx context.Context, in *GetTaskRequest, opts ...grpc.CallOption) (*Task, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Task)
+	err := c.cc.Invoke(ctx, CalcService_GetTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) SubmitResult(ctx context.Context, in *TaskResult, opts ...grpc.CallOption) (*SubmitResultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitResultResponse)
+	err := c.cc.Invoke(ctx, CalcService_SubmitResult_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterResponse)
+	err := c.cc.Invoke(ctx, CalcService_Register_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calcServiceClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HeartbeatResponse)
+	err := c.cc.Invoke(ctx, CalcService_Heartbeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CalcServiceServer is the server API for CalcService service.
 // All implementations must embed UnimplementedCalcServiceServer
 // for forward compatibility.
 type CalcServiceServer interface {
+	// вычисление одной операции агентом (оркестратор - клиент). если очередь
+	// агента заполнена, Calculation возвращает код RESOURCE_EXHAUSTED
 	Calculation(context.Context, *TaskRequest) (*ResResponse, error)
+	// агенты сами забирают задачи из очереди оркестратора и возвращают результаты.
+	// если задач нет, GetTask возвращает код NOT_FOUND
+	GetTask(context.Context, *GetTaskRequest) (*Task, error)
+	SubmitResult(context.Context, *TaskResult) (*SubmitResultResponse, error)
+	// агент сообщает о себе при запуске и затем периодически подтверждает, что жив.
+	// если оркестратор не знает агента (например, он был признан потерянным),
+	// Heartbeat возвращает код NOT_FOUND, и агент регистрируется заново
+	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
 	mustEmbedUnimplementedCalcServiceServer()
 }
 
@@ -65,6 +131,18 @@ type UnimplementedCalcServiceServer struct{}
 func (UnimplementedCalcServiceServer) Calculation(context.Context, *TaskRequest) (*ResResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Calculation not implemented")
 }
+func (UnimplementedCalcServiceServer) GetTask(context.Context, *GetTaskRequest) (*Task, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+}
+func (UnimplementedCalcServiceServer) SubmitResult(context.Context, *TaskResult) (*SubmitResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitResult not implemented")
+}
+func (UnimplementedCalcServiceServer) Register(context.Context, *RegisterRequest) (*RegisterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedCalcServiceServer) Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Heartbeat not implemented")
+}
 func (UnimplementedCalcServiceServer) mustEmbedUnimplementedCalcServiceServer() {}
 func (UnimplementedCalcServiceServer) testEmbeddedByValue()                     {}
 
@@ -104,6 +182,78 @@ func _CalcService_Calculation_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CalcService_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_GetTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).GetTask(ctx, req.(*GetTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_SubmitResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskResult)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).SubmitResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_SubmitResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).SubmitResult(ctx, req.(*TaskResult))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalcService_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalcServiceServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CalcService_Heartbeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalcServiceServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CalcService_ServiceDesc is the grpc.ServiceDesc for CalcService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,6 +265,22 @@ var CalcService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Calculation",
 			Handler:    _CalcService_Calculation_Handler,
 		},
+		{
+			MethodName: "GetTask",
+			Handler:    _CalcService_GetTask_Handler,
+		},
+		{
+			MethodName: "SubmitResult",
+			Handler:    _CalcService_SubmitResult_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _CalcService_Register_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _CalcService_Heartbeat_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/calc.proto",
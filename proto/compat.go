@@ -0,0 +1,83 @@
+package proto
+
+// совместимость с версиями протокола, в которых числа передавались только во float-полях.
+// новые отправители заполняют и float-, и double-поля, получатели берут double-поле,
+// если оно задано, иначе float-поле старого отправителя
+
+// функция для создания запроса на вычисление операции
+func NewTaskRequest(op string, x, y float64) *TaskRequest {
+	return &TaskRequest{
+		Arg1:       float32(x),
+		Arg2:       float32(y),
+		Opr:        op,
+		Arg1Double: &x,
+		Arg2Double: &y,
+	}
+}
+
+// аргументы операции
+func (x *TaskRequest) Args() (float64, float64) {
+	return pick(x.Arg1Double, x.GetArg1()), pick(x.Arg2Double, x.GetArg2())
+}
+
+// функция для создания ответа с результатом операции
+func NewResResponse(res float64) *ResResponse {
+	return &ResResponse{
+		Res:       float32(res),
+		ResDouble: &res,
+	}
+}
+
+// результат операции
+func (x *ResResponse) Result() float64 {
+	return pick(x.ResDouble, x.GetRes())
+}
+
+// функция для создания задачи из очереди оркестратора
+func NewTask(id string, op string, x, y float64) *Task {
+	return &Task{
+		Id:         id,
+		Arg1:       float32(x),
+		Arg2:       float32(y),
+		Opr:        op,
+		Arg1Double: &x,
+		Arg2Double: &y,
+	}
+}
+
+// аргументы задачи
+func (x *Task) Args() (float64, float64) {
+	return pick(x.Arg1Double, x.GetArg1()), pick(x.Arg2Double, x.GetArg2())
+}
+
+// запрос на вычисление задачи: аргументы и параметры точного режима
+func (x *Task) Request() *TaskRequest {
+	a, b := x.Args()
+	req := NewTaskRequest(x.GetOpr(), a, b)
+	req.Mode = x.GetMode()
+	req.Arg1Exact = x.GetArg1Exact()
+	req.Arg2Exact = x.GetArg2Exact()
+	req.Scale = x.GetScale()
+	req.Rounding = x.GetRounding()
+	req.Arg1Imag = x.Arg1Imag
+	req.Arg2Imag = x.Arg2Imag
+	return req
+}
+
+// функция для записи результата задачи в оба поля
+func (x *TaskResult) SetResult(res float64) {
+	x.Res = float32(res)
+	x.ResDouble = &res
+}
+
+// результат задачи
+func (x *TaskResult) Result() float64 {
+	return pick(x.ResDouble, x.GetRes())
+}
+
+func pick(v *float64, legacy float32) float64 {
+	if v != nil {
+		return *v
+	}
+	return float64(legacy)
+}